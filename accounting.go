@@ -0,0 +1,104 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"io"
+	"reflect"
+)
+
+// ResourceStat counts the io.Closers of one type, which have been registered
+// with OnCloseCloser(), see Closer.ResourceReport().
+type ResourceStat struct {
+	// Registered is the number of registered io.Closers.
+	Registered int
+	// Closed is the number of io.Closers, which closed without an error.
+	Closed int
+	// Failed is the number of io.Closers, which returned an error or panicked.
+	Failed int
+}
+
+// ResourceType returns the name of the dynamic type of cl, e.g. "*os.File",
+// as used by Closer.ResourceReport().
+func ResourceType(cl io.Closer) string {
+	return reflect.TypeOf(cl).String()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseCloser(closers ...io.Closer) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.checkMaxCloseFuncs(len(closers))
+	for _, cl := range closers {
+		if !c.opts.resourceAccounting {
+			c.closeFuncs = append(c.closeFuncs, cl.Close)
+			continue
+		}
+
+		typ := ResourceType(cl)
+		if c.resourceStats == nil {
+			c.resourceStats = make(map[string]*ResourceStat)
+		}
+		s := c.resourceStats[typ]
+		if s == nil {
+			s = &ResourceStat{}
+			c.resourceStats[typ] = s
+		}
+		s.Registered++
+
+		closeFunc := cl.Close
+		c.closeFuncs = append(c.closeFuncs, func() error {
+			err := callCloseFunc(closeFunc)
+
+			c.mx.Lock()
+			if err != nil {
+				s.Failed++
+			} else {
+				s.Closed++
+			}
+			c.mx.Unlock()
+			return err
+		})
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) ResourceReport() map[string]ResourceStat {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.opts.resourceAccounting {
+		return nil
+	}
+	report := make(map[string]ResourceStat, len(c.resourceStats))
+	for typ, s := range c.resourceStats {
+		report[typ] = *s
+	}
+	return report
+}
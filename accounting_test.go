@@ -0,0 +1,87 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+type testConn struct {
+	err error
+}
+
+func (c *testConn) Close() error {
+	return c.err
+}
+
+type testFile struct{}
+
+func (testFile) Close() error {
+	panic("close")
+}
+
+func TestCloser_ResourceReport(t *testing.T) {
+	t.Parallel()
+
+	errClose := errors.New("close")
+
+	c := closer.New(closer.WithResourceAccounting())
+	for i := 0; i < 40; i++ {
+		conn := &testConn{}
+		if i%10 == 0 {
+			conn.err = errClose
+		}
+		c.OnCloseCloser(conn)
+	}
+	c.OnCloseCloser(testFile{}, testFile{})
+
+	r.Equal(t, "*closer_test.testConn", closer.ResourceType(&testConn{}))
+	r.Equal(t, map[string]closer.ResourceStat{
+		"*closer_test.testConn": {Registered: 40},
+		"closer_test.testFile":  {Registered: 2},
+	}, c.ResourceReport())
+
+	err := c.Close()
+	r.ErrorIs(t, err, errClose)
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.Equal(t, map[string]closer.ResourceStat{
+		"*closer_test.testConn": {Registered: 40, Closed: 36, Failed: 4},
+		"closer_test.testFile":  {Registered: 2, Failed: 2},
+	}, c.ResourceReport())
+
+	// Without accounting, the io.Closers are closed, but not recorded.
+	conn := &testConn{err: errClose}
+	c = closer.New()
+	c.OnCloseCloser(conn)
+	r.ErrorIs(t, c.Close(), errClose)
+	r.Nil(t, c.ResourceReport())
+}
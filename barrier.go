@@ -0,0 +1,70 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "time"
+
+// DefaultBarrierTimeout is the timeout of Barrier().
+const DefaultBarrierTimeout = 30 * time.Second
+
+// Barrier synchronizes the closing of the given closers.
+// It registers an OnClosing func on each closer, which blocks until all
+// closers are closing. Therefore, no closer proceeds in its closing order
+// until all of them have reached the closing state. The barrier only waits,
+// it does not close any of the closers.
+// If not all closers are closing within DefaultBarrierTimeout, the blocked
+// OnClosing funcs return ErrBarrierTimeout and the closers continue to close.
+// See BarrierTimeout() to set another timeout.
+//
+// The closers must be closed concurrently. If they are closed one after
+// another, e.g. by their common parent, the barrier runs into its timeout.
+func Barrier(cs ...Closer) {
+	BarrierTimeout(DefaultBarrierTimeout, cs...)
+}
+
+// BarrierTimeout synchronizes the closing of the given closers like Barrier(),
+// but with the given timeout.
+func BarrierTimeout(timeout time.Duration, cs ...Closer) {
+	wait := func() error {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+
+		for _, c := range cs {
+			select {
+			case <-c.ClosingChan():
+			case <-t.C:
+				return ErrBarrierTimeout
+			}
+		}
+		return nil
+	}
+
+	for _, c := range cs {
+		c.OnClosing(wait)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestBarrier(t *testing.T) {
+	t.Parallel()
+
+	var (
+		closed     atomic.Int64
+		notClosing atomic.Int64
+		cs         = []closer.Closer{closer.New(), closer.New(), closer.New()}
+	)
+	closer.Barrier(cs...)
+
+	// No member may proceed, until all are closing.
+	for _, c := range cs {
+		c.OnClose(func() error {
+			for _, o := range cs {
+				if !o.IsClosing() {
+					notClosing.Add(1)
+				}
+			}
+			closed.Add(1)
+			return nil
+		})
+	}
+
+	// The barrier does not close the other members.
+	go cs[0].Close_()
+	go cs[1].Close_()
+	<-cs[0].ClosingChan()
+	<-cs[1].ClosingChan()
+	r.False(t, cs[2].IsClosing())
+	r.False(t, cs[0].IsClosed())
+	r.False(t, cs[1].IsClosed())
+
+	r.NoError(t, cs[2].Close())
+	for _, c := range cs {
+		select {
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out")
+		case <-c.ClosedChan():
+		}
+	}
+	r.Equal(t, int64(3), closed.Load())
+	r.Zero(t, notClosing.Load())
+}
+
+func TestBarrier_Timeout(t *testing.T) {
+	t.Parallel()
+
+	a, b := closer.New(), closer.New()
+	closer.BarrierTimeout(100*time.Millisecond, a, b)
+
+	// The open member is neither waited for forever nor closed.
+	r.ErrorIs(t, a.Close(), closer.ErrBarrierTimeout)
+	r.False(t, b.IsClosing())
+	r.NoError(t, b.Close())
+}
@@ -0,0 +1,74 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "fmt"
+
+// waitGroupStep is the step of the closing order, while Close() waits for
+// the wait group. BlockingReasons() adds the number of pending waits.
+const waitGroupStep = "waiting on wait group"
+
+// Implements the Closer interface.
+func (c *closer) BlockingReasons() []string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.IsClosing() || c.IsClosed() {
+		return nil
+	}
+
+	var reasons []string
+	switch c.closeStep {
+	case "":
+	case waitGroupStep:
+		reasons = append(reasons, fmt.Sprintf("%s (%d pending)", waitGroupStep, c.waitCount))
+	default:
+		reasons = append(reasons, c.closeStep)
+	}
+	for i, child := range c.closingChildren {
+		if child.IsClosed() {
+			continue
+		}
+		// Lock order: parent before child.
+		child.mx.Lock()
+		name := child.opts.name
+		child.mx.Unlock()
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		reasons = append(reasons, fmt.Sprintf("child '%s' still closing", name))
+	}
+	return reasons
+}
+
+// setCloseStep sets the current step of the closing order, see BlockingReasons().
+func (c *closer) setCloseStep(step string) {
+	c.mx.Lock()
+	c.closeStep = step
+	c.mx.Unlock()
+}
@@ -0,0 +1,99 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_BlockingReasons(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		db      = c.CloserOneWay(closer.WithName("db"))
+		release = make(chan struct{})
+		entered = make(chan struct{})
+	)
+	r.Nil(t, c.BlockingReasons())
+
+	// A blocked child.
+	db.OnClose(func() error {
+		close(entered)
+		<-release
+		return nil
+	})
+	var (
+		releaseFunc = make(chan struct{})
+		enteredFunc = make(chan struct{})
+	)
+	c.OnClose(func() error {
+		close(enteredFunc)
+		<-releaseFunc
+		return nil
+	})
+	c.OnClose(func() error { return nil })
+	c.CloserAddWait(3)
+
+	go c.Close_()
+	<-entered
+	r.Equal(t, []string{"child 'db' still closing"}, c.BlockingReasons())
+	r.Len(t, db.BlockingReasons(), 1)
+	r.True(t, strings.HasPrefix(db.BlockingReasons()[0], "in close func #1 ("))
+
+	// The wait group blocks, once the children have closed.
+	close(release)
+	<-db.ClosedChan()
+	r.Eventually(t, func() bool {
+		reasons := c.BlockingReasons()
+		return len(reasons) == 1 && reasons[0] == "waiting on wait group (3 pending)"
+	}, 3*time.Second, time.Millisecond)
+
+	c.CloserDone()
+	r.Equal(t, []string{"waiting on wait group (2 pending)"}, c.BlockingReasons())
+	c.CloserAddWait(-2)
+
+	// The second close func blocks in LIFO order.
+	<-enteredFunc
+	reasons := c.BlockingReasons()
+	r.Len(t, reasons, 1)
+	r.True(t, strings.HasPrefix(reasons[0], "in close func #2 ("), reasons[0])
+	close(releaseFunc)
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.Nil(t, c.BlockingReasons())
+}
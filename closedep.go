@@ -0,0 +1,107 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// A CloseFuncHandle references a close func registered with OnCloseDep().
+// It is only valid for the closer, which returned it.
+type CloseFuncHandle int
+
+// Implements the Closer interface.
+func (c *closer) OnCloseDep(f CloseFunc, after ...CloseFuncHandle) CloseFuncHandle {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.checkMaxCloseFuncs(1)
+	h := CloseFuncHandle(len(c.closeFuncs))
+	c.closeFuncs = append(c.closeFuncs, f)
+	c.addCloseFuncDeps(h, after)
+	return h
+}
+
+// Implements the Closer interface.
+func (c *closer) AddCloseDep(h CloseFuncHandle, after ...CloseFuncHandle) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.addCloseFuncDeps(h, after)
+}
+
+// addCloseFuncDeps records that the func of h runs after the funcs of after.
+// The closer's mutex must be locked.
+func (c *closer) addCloseFuncDeps(h CloseFuncHandle, after []CloseFuncHandle) {
+	if len(after) == 0 {
+		return
+	}
+	if c.closeFuncDeps == nil {
+		c.closeFuncDeps = make(map[int][]int)
+	}
+	for _, a := range after {
+		c.closeFuncDeps[int(h)] = append(c.closeFuncDeps[int(h)], int(a))
+	}
+}
+
+// orderCloseFuncs returns the execution order of n close funcs with the given
+// dependencies. Without dependencies, the order is LIFO. Otherwise, the latest
+// registered func, whose dependencies have been executed, is executed next.
+// Dependencies on unknown funcs are ignored. If the dependencies contain a cycle,
+// the funcs of the cycle are appended in LIFO order and cycle is true.
+func orderCloseFuncs(n int, deps map[int][]int) (order []int, cycle bool) {
+	order = make([]int, 0, n)
+	done := make([]bool, n)
+
+	ready := func(i int) bool {
+		for _, d := range deps[i] {
+			if d >= 0 && d < n && d != i && !done[d] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(order) < n {
+		next := -1
+		for i := n - 1; i >= 0; i-- {
+			if !done[i] && ready(i) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// The remaining funcs depend on each other.
+			for i := n - 1; i >= 0; i-- {
+				if !done[i] {
+					order = append(order, i)
+				}
+			}
+			return order, true
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order, false
+}
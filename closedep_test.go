@@ -0,0 +1,91 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_OnCloseDep(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c     = closer.New()
+		order []string
+	)
+	add := func(s string) closer.CloseFunc {
+		return func() error {
+			order = append(order, s)
+			return nil
+		}
+	}
+
+	// db must close after cache and server, although it is registered first.
+	db := c.OnCloseDep(add("db"))
+	c.OnClose(add("log"))
+	cache := c.OnCloseDep(add("cache"))
+	server := c.OnCloseDep(add("server"))
+	c.AddCloseDep(db, cache, server)
+	// cache must close after server.
+	c.AddCloseDep(cache, server)
+	// metrics is registered last, but must close after db.
+	c.OnCloseDep(add("metrics"), db)
+
+	plan := c.ClosePlan()
+	r.Len(t, plan, 5)
+
+	r.NoError(t, c.Close())
+	r.Equal(t, []string{"server", "cache", "log", "db", "metrics"}, order)
+}
+
+func TestCloser_OnCloseDepCycle(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c     = closer.New()
+		order []string
+	)
+	add := func(s string) closer.CloseFunc {
+		return func() error {
+			order = append(order, s)
+			return nil
+		}
+	}
+
+	a := c.OnCloseDep(add("a"))
+	b := c.OnCloseDep(add("b"), a)
+	c.AddCloseDep(a, b)
+	c.OnClose(add("c"))
+
+	// The funcs of the cycle are still executed.
+	r.ErrorIs(t, c.Close(), closer.ErrCloseFuncCycle)
+	r.Equal(t, []string{"c", "b", "a"}, order)
+}
@@ -0,0 +1,86 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// A CloseFuncSet bundles named close funcs, which can be applied to any closer
+// with Closer.Apply(). This allows to reuse teardown logic across closers.
+// The zero value is an empty set. A set must not be modified concurrently.
+type CloseFuncSet struct {
+	names []string
+	funcs []CloseFunc
+}
+
+// Add adds the named close func to the set and returns the set.
+func (s *CloseFuncSet) Add(name string, f CloseFunc) *CloseFuncSet {
+	s.names = append(s.names, name)
+	s.funcs = append(s.funcs, f)
+	return s
+}
+
+// Merge returns a new set with the funcs of this set, followed by the funcs of
+// the other sets. None of the sets is modified.
+func (s *CloseFuncSet) Merge(others ...*CloseFuncSet) *CloseFuncSet {
+	merged := &CloseFuncSet{}
+	for _, o := range append([]*CloseFuncSet{s}, others...) {
+		if o == nil {
+			continue
+		}
+		merged.names = append(merged.names, o.names...)
+		merged.funcs = append(merged.funcs, o.funcs...)
+	}
+	return merged
+}
+
+// Len returns the number of funcs of the set.
+func (s *CloseFuncSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.funcs)
+}
+
+// Names returns the names of the funcs in the order they were added.
+func (s *CloseFuncSet) Names() []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s.names...)
+}
+
+// Funcs returns the funcs in the order they were added.
+func (s *CloseFuncSet) Funcs() []CloseFunc {
+	if s == nil {
+		return nil
+	}
+	return append([]CloseFunc(nil), s.funcs...)
+}
+
+// Implements the Closer interface.
+func (c *closer) Apply(set *CloseFuncSet) {
+	c.OnClose(set.Funcs()...)
+}
@@ -0,0 +1,89 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloseFuncSet(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mx     sync.Mutex
+		order  []string
+		errLog = errors.New("log")
+	)
+	step := func(name string, err error) closer.CloseFunc {
+		return func() error {
+			mx.Lock()
+			order = append(order, name)
+			mx.Unlock()
+			return err
+		}
+	}
+
+	var storage closer.CloseFuncSet
+	storage.Add("db", step("db", nil)).Add("cache", step("cache", nil))
+	logging := (&closer.CloseFuncSet{}).Add("log", step("log", errLog))
+
+	merged := storage.Merge(logging, nil)
+	r.Equal(t, []string{"db", "cache", "log"}, merged.Names())
+	r.Equal(t, 3, merged.Len())
+	// Merge does not modify the sets.
+	r.Equal(t, 2, storage.Len())
+	r.Equal(t, 1, logging.Len())
+
+	// Apply the same sets to several closers.
+	c1 := closer.New()
+	c1.Apply(&storage)
+	c2 := closer.New()
+	c2.Apply(merged)
+
+	r.NoError(t, c1.Close())
+	r.Equal(t, []string{"cache", "db"}, order)
+
+	order = nil
+	r.ErrorIs(t, c2.Close(), errLog)
+	r.Equal(t, []string{"log", "cache", "db"}, order)
+
+	// Empty and nil sets.
+	var nilSet *closer.CloseFuncSet
+	r.Zero(t, nilSet.Len())
+	r.Nil(t, nilSet.Names())
+	c := closer.New()
+	c.Apply(nilSet)
+	c.Apply(&closer.CloseFuncSet{})
+	r.Zero(t, c.Stats().NumCloseFuncs)
+	r.NoError(t, c.Close())
+}
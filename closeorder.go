@@ -0,0 +1,68 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Implements the Closer interface.
+func (c *closer) OnCloseNeeds(f CloseFunc, needs ...Closer) {
+	if !debugEnabled || len(needs) == 0 {
+		c.OnClose(f)
+		return
+	}
+
+	name := funcName(f)
+	c.OnClose(func() error {
+		err := validateCloseOrder(name, needs)
+		if err != nil {
+			// Use fmt instead of log for additional new line printing.
+			fmt.Fprintf(os.Stderr, "\nDEBUG: %v\n\n", err)
+		}
+		return errors.Join(err, f())
+	})
+}
+
+// validateCloseOrder returns an error wrapping ErrCloseOrder,
+// if one of the needed closers of the close func is already closed.
+func validateCloseOrder(name string, needs []Closer) (err error) {
+	for _, n := range needs {
+		if !n.IsClosed() {
+			continue
+		}
+		needName := n.Name()
+		if needName == "" {
+			needName = "unnamed closer"
+		}
+		err = errors.Join(err, fmt.Errorf("%w: close func %s needs '%s', which is already closed", ErrCloseOrder, name, needName))
+	}
+	return
+}
@@ -47,14 +47,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// ErrClosed is a generic error that indicates a resource has been closed.
-var ErrClosed = errors.New("closed")
+var (
+	// ErrClosed is a generic error that indicates a resource has been closed.
+	ErrClosed = errors.New("closed")
+
+	// ErrBarrierTimeout indicates that not all members of a barrier were closing in time.
+	ErrBarrierTimeout = errors.New("barrier timeout")
+
+	// ErrClosing indicates that a closer is already closing.
+	ErrClosing = errors.New("closing")
+
+	// ErrPanic indicates that a panic has been recovered.
+	ErrPanic = errors.New("panic")
+
+	// ErrUnknownCloser indicates that a Closer has not been created by this package.
+	ErrUnknownCloser = errors.New("unknown closer implementation")
+
+	// ErrDependencyTimeout indicates that a closer did not close in time,
+	// which another closer depends on, see CloseAfter().
+	ErrDependencyTimeout = errors.New("close dependency timeout")
+
+	// ErrDeadlineExceeded indicates that a closer has been closed, because its deadline passed.
+	ErrDeadlineExceeded = errors.New("deadline exceeded")
+
+	// ErrChildNotFound indicates that a closer has no child with the requested name.
+	ErrChildNotFound = errors.New("child not found")
+
+	// ErrCloseFuncTimeout indicates that a close func did not return in time,
+	// see OnCloseTimeout().
+	ErrCloseFuncTimeout = errors.New("close func timeout")
+
+	// ErrCloseFuncCycle indicates that the dependencies between close funcs
+	// contain a cycle, see OnCloseDep().
+	ErrCloseFuncCycle = errors.New("cyclic close func dependencies")
+
+	// ErrCycle indicates that an operation would create a cyclic closer relationship.
+	ErrCycle = errors.New("cyclic closer relationship")
+
+	// ErrWaitTimeout indicates that the wait group did not finish in time,
+	// see CloseWithWaitTimeout().
+	ErrWaitTimeout = errors.New("wait timeout")
+
+	// ErrCloseOrder indicates that a close func has been executed after a closer
+	// it needs had already closed, see OnCloseNeeds().
+	ErrCloseOrder = errors.New("close order violation")
+)
 
 //#############//
 //### Types ###//
@@ -63,6 +109,39 @@ var ErrClosed = errors.New("closed")
 // CloseFunc defines the general close function.
 type CloseFunc func() error
 
+// CloseCtxFunc defines a close function, which receives the context of the close.
+type CloseCtxFunc func(ctx context.Context) error
+
+// FinalFunc defines a close function, which receives all errors
+// that occurred during the closing order so far.
+type FinalFunc func(errs []error) error
+
+// State describes the lifecycle state of a closer.
+type State int
+
+const (
+	// StateOpen indicates that the closer is not closing.
+	StateOpen State = iota
+	// StateClosing indicates that the closer is closing, but not yet closed.
+	StateClosing
+	// StateClosed indicates that the closer has been closed completely.
+	StateClosed
+)
+
+// String implements the fmt.Stringer interface.
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateClosing:
+		return "closing"
+	case StateClosed:
+		return "closed"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
 //#################//
 //### Interface ###//
 //#################//
@@ -79,21 +158,103 @@ type Closer interface {
 	// The closing order looks like this:
 	// 1: the closing chan is closed.
 	// 2: the OnClosing funcs are executed.
-	// 3: each of the closer's children is closed.
-	// 4: it waits for the wait group.
-	// 5: the OnClose funcs are executed.
-	// 6: the closed chan is closed.
-	// 7: the parent is closed, if it has one.
+	// 3: the OnBeforeChildrenClose funcs are executed.
+	// 4: each of the closer's children is closed, see WithConcurrentChildClose().
+	// 5: it waits for the wait group.
+	// 6: it waits for the closers registered with CloseAfter.
+	// 7: the OnClose funcs are executed.
+	// 8: the OnCloseFinal funcs are executed.
+	// 9: the OnCloseLast funcs are executed.
+	// 10: the closed chan is closed.
+	// 11: the parent is closed, if it has one.
 	//
-	// Close blocks, until step 6 of the closing order
+	// Close blocks, until step 10 of the closing order
 	// has been finished. A potential parent gets
 	// closed concurrently in a new goroutine.
 	//
 	// The returned error contains the joined errors of all closers that were part of
 	// the blocking closing order of this closer.
 	// This means that two-way closers do not report their parents' errors.
+	//
+	// A panic in any OnClosing or OnClose func is recovered and
+	// joined as error wrapping ErrPanic. The remaining funcs are still executed.
+	//
+	// Calling Close() or CloseCtx() from within a close func, while the closer
+	// executes its closing order on the same goroutine, would block forever.
+	// Instead, it panics with "closer: reentrant Close from within a close func",
+	// which is recovered like any other panic of a close func. Close the closer
+	// in a new goroutine instead, if required. Closing another closer from within
+	// a close func, which is concurrently closing, is not affected.
 	Close() error
 
+	// CloseAndReturnFirst closes the closer like Close(), but returns only the first error.
+	// All errors are still joined and returned by CloserError().
+	// The first error is determined in this order:
+	// 1: the first error passed to the closer before it started closing, e.g. by CloseWithErr().
+	// 2: the first error of the closing order in execution order. For a child, this is
+	//    the first error of the child, determined in the same way.
+	// 3: the errors passed to the closer while it is closing.
+	CloseAndReturnFirst() error
+
+	// CloseBottomUp closes the subtree of this closer strictly bottom-up and returns
+	// the joined errors of all its closers. Each child is detached and closed bottom-up,
+	// before the closing order of its parent starts. Thus, the closing chan of a closer
+	// is closed only after all of its descendants have been closed completely.
+	// In contrast, Close() signals closing to the whole subtree first.
+	// Two-way children do not close their parents. Children added during the
+	// bottom-up close are closed in the regular closing order.
+	CloseBottomUp() error
+
+	// CloseTree closes the closer like Close() and returns the result of each closer
+	// of the tree, which has been closed by this closer's closing order.
+	// In contrast to the joined error returned by Close(), each node of the result
+	// only carries the errors of its own closer, so failures can be mapped to positions
+	// in the tree. Children that closed on their own before are not part of the result.
+	CloseTree() TreeResult
+
+	// CloseAndCollect closes the closer like Close() and returns the outcome of each
+	// step of its closing order, in the order of execution. The steps are named like
+	// the steps of ClosePlan(). Steps skipped in fail fast mode are not part of the result.
+	// The result is only collected, if this call starts the close. Otherwise, the
+	// results of a previous CloseAndCollect() call are returned, if any.
+	CloseAndCollect() []StepResult
+
+	// ClosingDeadline returns the effective deadline of the current close and true,
+	// or false, if the close is unbounded or the closer is not yet closing.
+	// The deadline is the earliest of the deadline of the close context, see CloseCtx()
+	// and SetCloseTimeout(), and the closer's deadline, see SetDeadline(), if it
+	// has not passed when the closing started. Close funcs and routines can use
+	// it to limit themselves.
+	ClosingDeadline() (time.Time, bool)
+
+	// CloseWithWaitTimeout performs the same operation as Close(), but bounds only
+	// the wait for this closer's wait group by the given duration. If the wait group
+	// does not finish in time, ErrWaitTimeout is joined with the closer's other errors
+	// and the closing order continues. The close funcs are still executed without any
+	// time limit. This allows to give up on stuck routines, but not on the cleanup.
+	// The children are closed as usual.
+	CloseWithWaitTimeout(d time.Duration) error
+
+	// CloseWithRetry performs the same operation as Close(), but retries each OnClose
+	// func, which returned an error, up to the given number of attempts in total,
+	// waiting for the backoff between the attempts. Funcs that succeeded are not
+	// executed again, so already released resources are not released twice.
+	// Only the errors of the last attempts are joined with the closer's other errors.
+	// The retries are bounded by the close context, see CloseCtx(), and apply only
+	// to this closer, not to its children.
+	CloseWithRetry(attempts int, backoff time.Duration) error
+
+	// CloseCtx performs the same operation as Close(), but the context bounds
+	// the closing order. Once the context is done, waiting for the wait group,
+	// the children's wait groups and the closers registered with CloseAfter is aborted.
+	// The remaining steps of the closing order are still executed and the closed chan
+	// is closed. The context is passed to the funcs registered with OnCloseCtx, which
+	// should abort, once it is done. If the context is done during the closing order,
+	// its error is joined with the closer's other errors.
+	// If another call is already closing the closer, CloseCtx waits for the closer
+	// to close or returns the context's error, if the context is done first.
+	CloseCtx(ctx context.Context) error
+
 	// Close_ is a convenience version of Close(), for use in defer
 	// where the error is not of interest.
 	Close_()
@@ -101,6 +262,50 @@ type Closer interface {
 	// CloseWithErr closes the closer and appends the given error to its joined error.
 	CloseWithErr(err error)
 
+	// AppendCloseErr adds the given error to the joined error of CloserError(),
+	// without closing the closer. In contrast to CloseWithErr(), the error is
+	// neither the cause of the close nor returned by CloseAndReturnFirst().
+	// The appended errors follow the errors of the closing order. This allows to
+	// collect warnings during the lifetime of the closer, which are reported
+	// with the close result. Nil errors and errors appended to an already closed
+	// closer are ignored.
+	AppendCloseErr(err error)
+
+	// CloseWithReason closes the closer and records the given human-readable
+	// reason for the close, e.g. "SIGTERM received". In contrast to an error,
+	// a reason describes also graceful closes.
+	// Only the first reason is recorded and only if the closer was not yet closing.
+	CloseWithReason(reason string) error
+
+	// ClosedByParent returns true, if the close of this closer has been initiated
+	// by its parent, either because the parent is closing or by its CloseChildren().
+	// It returns false, if the closer has been closed directly or is not yet closing.
+	// It is intended to be used within the closer's close funcs.
+	ClosedByParent() bool
+
+	// CloseReasonText returns the reason recorded by CloseWithReason.
+	// Returns an empty string, if no reason has been recorded.
+	CloseReasonText() string
+
+	// CloseStatus returns the close reason, see CloseReasonText(), the close error,
+	// see CloserError(), and the close duration, see CloseDuration(), as a consistent
+	// snapshot, e.g. for a single summary log line.
+	// Returns zero values, if the closer is not yet closed.
+	CloseStatus() (reason string, err error, duration time.Duration)
+
+	// InitiatedBy returns the origin of the close and the closer, which initiated it.
+	// The closer is nil for a direct close and returned for the parent of OriginParent,
+	// the two-way child of OriginChild and the linked closer of OriginLink.
+	// Returns OriginNone, if the closer is not yet closing.
+	InitiatedBy() (Origin, Closer)
+
+	// Link links the fate of this closer and the other closer. Once one of both
+	// starts closing, the other one is closed as well without waiting for it.
+	// The linked close is recorded by InitiatedBy() and CloseReasonText() reports
+	// "link: <name>" with the name of the initiating closer, if no other reason has been
+	// recorded. If one of both is already closing, the other one is closed immediately.
+	Link(other Closer)
+
 	// CloseWithErrAndDone performs the same operation as CloseWithErr(), but decrements
 	// the closer's wait group by one beforehand.
 	// Attention: Calling this without first calling CloserAddWait results in a panic.
@@ -115,6 +320,47 @@ type Closer interface {
 	// defer where the error is not of interest.
 	CloseAndDone_()
 
+	// CloseParentOnly appends the given error to the parent's joined error and closes
+	// the parent in a new goroutine, without closing this closer and its children first.
+	// This escalates a condition to the parent, regardless of a one-way or two-way relationship.
+	//
+	// When this method returns, this closer and its children are still open.
+	// They are closed by the parent, as soon as it reaches its children in the closing order.
+	// If the parent is already closing or the closer has no parent, this is a no-op.
+	CloseParentOnly(err error)
+
+	// CloseChildren closes all current children of this closer and returns their joined errors.
+	// The children are removed from this closer, which itself remains open.
+	// Its OnClosing and OnClose funcs are not executed. Two-way children do not close this closer.
+	// Afterwards, new children can be created.
+	// Returns ErrClosing, if the closer is already closing.
+	CloseChildren() error
+
+	// CloseExcept closes the closer like Close(), but keeps the given descendants
+	// and their subtrees open. The kept closers are detached from their parents
+	// beforehand and become root closers. Thus, they are neither closed by the
+	// closing order nor do kept two-way children close their former parents.
+	// Closers, which are not descendants of this closer, are ignored.
+	CloseExcept(keep ...Closer) error
+
+	// CloseSiblings closes all other children of this closer's parent and returns their joined errors.
+	// The siblings are removed from the parent, which remains open, just like this closer.
+	// Two-way siblings do not close the parent.
+	// Returns nil, if the closer has no parent, and ErrClosing, if the parent is already closing.
+	CloseSiblings() error
+
+	// CloseAfter lets this closer wait for the other closer to close completely,
+	// before executing its OnClose funcs. This expresses close dependencies
+	// between closers of different branches of a closer tree.
+	// If both closers are closed by a common ancestor, the branch of the ancestor
+	// containing the other closer is closed concurrently, once this closer waits for it.
+	// If the other closer does not close within the timeout, the closer
+	// proceeds and ErrDependencyTimeout is joined with its other errors.
+	// See Close() for the position in the closing order.
+	// Returns ErrCycle, if the other closer is this closer, one of its ancestors
+	// or depends on this closer itself, and ErrClosing, if this closer is already closing.
+	CloseAfter(other Closer, timeout time.Duration) error
+
 	// CloserAddWait adds the given delta to the closer's
 	// wait group. Useful to wait for routines associated
 	// with this closer to gracefully shutdown.
@@ -122,36 +368,180 @@ type Closer interface {
 	CloserAddWait(delta int)
 
 	// CloserDone decrements the closer's wait group by one.
-	// Attention: Calling this without first calling CloserAddWait results in a panic.
+	// Attention: Calling this without first calling CloserAddWait results in a panic,
+	// unless configured otherwise with WithDoneUnderflowPolicy().
 	CloserDone()
 
+	// PendingWaitStacks returns the stack traces of the call sites that added to
+	// the closer's wait group, which have not yet been matched by CloserDone calls.
+	// Stack traces are only recorded if build with the closer_debug tag,
+	// otherwise nil is returned.
+	// Since CloserDone calls can not be mapped to their call sites, they are matched
+	// with the oldest pending call sites. Call sites of BlockCloser, RunCloserRoutine
+	// and RunEvery are recorded as well.
+	PendingWaitStacks() []string
+
 	// CloserOneWay creates a new child closer that has a one-way relationship
 	// with the current closer. This means that the child is closed whenever
 	// the parent closes, but not vice versa.
+	// The child inherits the fail fast and done underflow policies, the logger,
+	// a copy of the labels, the error hook and the close tracer of the parent, which can be overridden
+	// by the given options. Name, deadline and close timeout are not inherited.
 	// See Close() for the position in the closing order.
-	CloserOneWay() Closer
+	CloserOneWay(opts ...Option) Closer
 
 	// CloserTwoWay creates a new child closer that has a two-way relationship
 	// with the current closer. This means that the child is closed whenever
 	// the parent closes and vice versa.
+	// The child inherits the parent's policies like CloserOneWay().
 	// See Close() for the position in the closing order.
-	CloserTwoWay() Closer
+	CloserTwoWay(opts ...Option) Closer
+
+	// CloserTwoWayIf creates a new child closer like CloserTwoWay(), but the
+	// child only closes its parent, if the predicate returns true.
+	// The predicate is called with the child, once it has closed, so it may
+	// inspect its state like CloserError() to decide on the propagation.
+	// A nil predicate always propagates.
+	CloserTwoWayIf(predicate func(child Closer) bool, opts ...Option) Closer
+
+	// Clone creates a new, independent root closer with the same options as this
+	// closer, including its name, labels and current close timeout, see SetCloseTimeout().
+	// Only the configuration is copied. The clone does not share any state with
+	// this closer. Its funcs, children, wait group and errors are not copied.
+	Clone() Closer
+
+	// SetDeadline sets the point in time when the closer automatically closes
+	// with ErrDeadlineExceeded. It replaces any previously set deadline,
+	// including one set with WithDeadline(), and can therefore extend or shorten it.
+	// A zero time removes the deadline.
+	// Returns ErrClosing, if the closer is already closing.
+	SetDeadline(t time.Time) error
+
+	// SetCloseTimeout sets the timeout that bounds the closing order of Close()
+	// and the other close methods without a context, like CloseCtx() with a
+	// context that times out after d. It replaces the timeout set with WithCloseTimeout().
+	// A timeout of zero or less disables the timeout.
+	// Returns ErrClosing, if the closer is already closing.
+	SetCloseTimeout(d time.Duration) error
+
+	// OnChildAdded adds the given func, which is called whenever a child is added
+	// to this closer, either by creating a new child or by Adopt().
+	// The func is called outside of the closer's lock.
+	OnChildAdded(f func(child Closer))
+
+	// OnChildRemoved adds the given func, which is called whenever a child is removed
+	// from this closer, because it closed, was moved by Adopt() or detached by CloseChildren().
+	// Children closed by this closer's Close() are not reported.
+	// The func is called outside of the closer's lock.
+	OnChildRemoved(f func(child Closer))
+
+	// SuspendTwoWay suspends the two-way relationship of all children of this closer.
+	// Two-way children closing during the suspension do not close this closer.
+	// Instead, they are removed like one-way children.
+	// This allows to close children in a controlled order before closing this closer.
+	SuspendTwoWay()
+
+	// ResumeTwoWay resumes the two-way relationship of all children of this closer.
+	// Children that closed during the suspension do not close this closer afterwards.
+	ResumeTwoWay()
+
+	// SetTwoWay changes the relationship of this closer to its parent at runtime.
+	// If true, the closer closes its parent like a child created with CloserTwoWay(),
+	// otherwise it closes without closing its parent like a child created with
+	// CloserOneWay(). This models children, whose failure is only fatal for a while,
+	// e.g. during their initialization. The parent always closes its children.
+	// The predicate of CloserTwoWayIf() is kept.
+	// Returns ErrClosing, if the closer is already closing.
+	SetTwoWay(twoWay bool) error
 
 	// Context returns a context.Context, which is cancelled
 	// as soon as the closer is closing.
+	// Its cause, see context.Cause(), contains the errors the closer has been closed with,
+	// e.g. by CloseWithErr(), or ErrClosed, if the closer has been closed without error.
 	// The returned cancel func should be called as soon as the
 	// context is no longer needed, to free resources.
 	Context() (context.Context, context.CancelFunc)
 
+	// WaitGroupContext returns a context, which is canceled as soon as the closer
+	// is closing, with the same cause as the context of Context().
+	// Pass it to blocking calls of routines of the wait group, so that they
+	// start unwinding and call CloserDone, once the closer is closing.
+	// In contrast to Context(), the context is shared and does not have to be released.
+	// See ClosedContext() for a context, which is canceled once the closer closed.
+	WaitGroupContext() context.Context
+
+	// ClosedContext returns a context, which is canceled as soon as the closer
+	// is closed completely, with the same cause as the context of Context().
+	// Its cause contains all errors of the closing order.
+	// The context is shared and does not have to be released.
+	ClosedContext() context.Context
+
 	// CloseOnContextDone closes the closer if the context is done.
 	CloseOnContextDone(context.Context)
 
+	// BindContextPoll closes the closer if the context is done, like CloseOnContextDone().
+	// Instead of a goroutine per closer, the contexts of many closers are watched by
+	// a few shared goroutines, which are only running while contexts are bound.
+	// Prefer it for large numbers of short-lived closers bound to contexts.
+	BindContextPoll(ctx context.Context)
+
+	// BeginClosing enters the reversible soft closing state and executes the OnClosing funcs.
+	// The SoftClosingChan() is closed, which signals routines to drain their work,
+	// whereas the ClosingChan() remains open. Returns the joined errors of the OnClosing funcs.
+	//
+	// The states are strictly ordered:
+	//   open -> soft closing -> closing -> closed
+	// AbortClosing() reverts the soft closing state to the open state,
+	// Close() proceeds to the closing state, which can not be reverted.
+	// OnClosing funcs executed by BeginClosing() are not executed again by Close(),
+	// unless the soft closing state has been aborted. Their errors are joined with the
+	// closer's other errors.
+	//
+	// Calling BeginClosing while in the soft closing state is a no-op.
+	// Returns ErrClosing, if the closer is already closing.
+	BeginClosing() error
+
+	// AbortClosing reverts the soft closing state entered by BeginClosing() to the open state.
+	// It waits for the OnClosing funcs of BeginClosing() to return, discards their errors and
+	// replaces the SoftClosingChan() with a new open channel.
+	// Calling AbortClosing in the open state is a no-op.
+	// Returns ErrClosing, once Close() has started.
+	AbortClosing() error
+
+	// SoftClosingChan returns a channel, which is closed as soon as the closer
+	// enters the soft closing state with BeginClosing() or is closing.
+	// On AbortClosing(), the channel is replaced; retrieve it again afterwards.
+	SoftClosingChan() <-chan struct{}
+
 	// ClosingChan returns a channel, which is closed as
 	// soon as the closer is about to close.
 	// Remains closed, once ClosedChan() has also been closed.
 	// See Close() for the position in the closing order.
 	ClosingChan() <-chan struct{}
 
+	// Pause pauses the closer without closing it. Routines should select on
+	// PausedChan() to stop processing temporarily and on ClosingChan() to exit.
+	// Pausing a paused or closing closer is a no-op. The wait group is not affected.
+	Pause()
+
+	// Resume resumes a paused closer, see Pause(). Resuming a closer, which is
+	// not paused, is a no-op. A closing closer can still be resumed.
+	Resume()
+
+	// PausedChan returns a channel, which is closed once the closer is paused.
+	// After a Resume(), a new channel is returned, which is closed by the next Pause().
+	PausedChan() <-chan struct{}
+
+	// ResumedChan returns a channel, which is closed while the closer is not paused.
+	// After a Pause(), a new channel is returned, which is closed by the next Resume().
+	// Paused routines should select on it and on ClosingChan() to continue.
+	ResumedChan() <-chan struct{}
+
+	// OnStopCh returns the ClosingChan(). It matches the stop channel
+	// convention of Kubernetes client-go and controller-runtime style APIs,
+	// which stop once the channel is closed.
+	OnStopCh() <-chan struct{}
+
 	// ClosedChan returns a channel, which is closed as
 	// soon as the closer is completely closed.
 	// See Close() for the position in the closing order.
@@ -166,12 +556,177 @@ type Closer interface {
 	// whether this instance has been closed completely.
 	IsClosed() bool
 
+	// IsCloseInProgress returns a boolean indicating
+	// whether this instance is closing, but not yet closed completely.
+	// This is the case while Close() executes the closing order.
+	IsCloseInProgress() bool
+
 	// OnClose adds the given CloseFuncs to the closer.
 	// Their errors are joined with the closer's other errors.
 	// Close functions are called in LIFO order.
+	// The number of close funcs can be limited with WithMaxCloseFuncs().
 	// See Close() for their position in the closing order.
 	OnClose(f ...CloseFunc)
 
+	// Apply adds all funcs of the set to the closer like OnClose in the order they
+	// were added to the set. Thus, they are executed in reverse order.
+	// Like OnClose funcs, funcs applied to an already closing closer are not executed.
+	Apply(set *CloseFuncSet)
+
+	// OnCloseBenign adds the given CloseFuncs to the closer like OnClose,
+	// but marks their errors as benign, see Benign() and SevereError().
+	OnCloseBenign(f ...CloseFunc)
+
+	// OnCloseCloser adds the Close methods of the given io.Closers to the closer
+	// like OnClose. With WithResourceAccounting(), the io.Closers are recorded by
+	// their type, see ResourceReport().
+	OnCloseCloser(cl ...io.Closer)
+
+	// OnCloseBroadcast notifies the given channel, as soon as the closer is closing,
+	// by a non-blocking send. This allows external components to react to the close,
+	// without becoming children. The channel should be buffered, because the
+	// notification is dropped, if it can not be sent immediately. Close never blocks
+	// on the channel. If the closer is already closing, the channel is notified immediately.
+	OnCloseBroadcast(ch chan<- struct{})
+
+	// DoOnClosed calls f exactly once, after the closer has been closed.
+	// If the closer is already closed, f is called immediately.
+	// Otherwise f is called in a new goroutine, right after the closed chan has been closed.
+	// In contrast to the close funcs, f is not part of the closing order and
+	// does not block Close(). A panic in f is recovered and discarded, because
+	// the close error is not modified after the closer has closed.
+	DoOnClosed(f func())
+
+	// OnCloseErr calls f exactly once with the close error like DoOnClosed().
+	// The error is the complete result of the closing order, including the
+	// errors of the children, and nil, if the closer closed cleanly.
+	OnCloseErr(f func(err error))
+
+	// CloseErrTo sends the close error like OnCloseErr() exactly once to ch,
+	// once the closer has closed. The error is nil, if the closer closed cleanly.
+	// This allows to collect the close results of many closers with one channel.
+	// The send does not block and the error is dropped, if it can not be sent
+	// immediately. Hence, the channel should be buffered with room for one error
+	// for each closer sending to it. A nil channel is ignored.
+	CloseErrTo(ch chan<- error)
+
+	// SetFinalizer sets f, which Close() calls with the close error while holding
+	// the closer's mutex, immediately before the closed chan is closed. Thus, f runs
+	// before any waiter observes the close, which DoOnClosed() and OnCloseErr() can
+	// not guarantee. f must not block and must not call any method of the closer.
+	// A panic in f is recovered and joined with the close error.
+	// Only one finalizer is set, a further call replaces it. If the closer is
+	// already closed, f is called immediately.
+	SetFinalizer(f func(err error))
+
+	// OnCloseDep adds the given CloseFunc to the closer like OnClose and returns
+	// a handle to reference it. The func is executed after the close funcs of the
+	// given handles, regardless of the registration order. All other close funcs
+	// keep their LIFO order, as far as the dependencies allow.
+	// See AddCloseDep() to add dependencies to an already registered func.
+	// If the dependencies contain a cycle, Close() joins ErrCloseFuncCycle
+	// with the closer's other errors and executes the funcs of the cycle in LIFO order.
+	// See Close() for its position in the closing order.
+	OnCloseDep(f CloseFunc, after ...CloseFuncHandle) CloseFuncHandle
+
+	// OnCloseNeeds adds the given CloseFunc to the closer like OnClose and declares,
+	// that f needs the given closers to be still open, e.g. to flush data to a
+	// database. Builds with the closer_debug tag validate the closing order: If a
+	// needed closer is already closed, once f is executed, an error wrapping
+	// ErrCloseOrder is joined with the closer's other errors and logged to stderr.
+	// f is executed anyway. Without the tag, the closers are not validated, so
+	// this is a runtime check of the teardown order for tests without production cost.
+	// See Close() for its position in the closing order.
+	OnCloseNeeds(f CloseFunc, needs ...Closer)
+
+	// AddCloseDep lets the close func of the handle run after the close funcs
+	// of the given handles, see OnCloseDep().
+	AddCloseDep(h CloseFuncHandle, after ...CloseFuncHandle)
+
+	// Phase returns the named phase of the closer to register close funcs into.
+	// The funcs of a phase are executed like OnClose funcs in LIFO order within
+	// the phase. The close funcs without a phase are executed first, followed by
+	// the phases in the order of SetPhaseOrder(). Phases without a set order are
+	// executed last. The phase order takes precedence over OnCloseDep() dependencies.
+	Phase(name string) ClosePhase
+
+	// SetPhaseOrder sets the order, in which the given phases are executed during
+	// the close, see Phase(). It replaces a previously set order.
+	SetPhaseOrder(names ...string)
+
+	// OnCloseCtx adds the given CloseCtxFuncs to the closer like OnClose.
+	// They receive the context passed to CloseCtx, the context bounded by the close
+	// timeout of Close(), see SetCloseTimeout(), or a context that is never done
+	// for any other close, and should abort, once the context is done.
+	// The children close with the same context.
+	// See Close() for their position in the closing order.
+	OnCloseCtx(f ...CloseCtxFunc)
+
+	// OnClosingCtx adds the given CloseCtxFuncs to the closer like OnClosing.
+	// They receive the same context as the funcs of OnCloseCtx() and should abort,
+	// once the context is done. If executed by BeginClosing(), the context is never done.
+	// See Close() for their position in the closing order.
+	OnClosingCtx(f ...CloseCtxFunc)
+
+	// OnCloseTimeout adds the given CloseFunc to the closer like OnClose,
+	// but bounds its execution to the given duration.
+	// If the func does not return in time, ErrCloseFuncTimeout is joined with the
+	// closer's other errors and the closing order continues. The func keeps running
+	// in the background and its error is discarded.
+	// See Close() for its position in the closing order.
+	OnCloseTimeout(d time.Duration, f CloseFunc)
+
+	// Defer adds the given func to the close funcs of the closer,
+	// like OnClose does with a CloseFunc.
+	// Just like Go's defer statement, the funcs are executed in LIFO order.
+	// This allows to replace a stack of defer statements with one closer:
+	//  c := closer.New()
+	//  defer c.Close_()
+	//  c.Defer(cleanup)
+	// See Close() for their position in the closing order.
+	Defer(f func())
+
+	// OnCloseOnce adds the given CloseFunc to the closer, guarded to be executed
+	// at most once. The guarded func is returned and can be registered on further
+	// closers, e.g. with OnClose. Regardless of how often it is registered and called,
+	// f is executed only once. Only the first call returns the error of f.
+	OnCloseOnce(f CloseFunc) CloseFunc
+
+	// OnCloseIf adds the given CloseFunc to the closer like OnClose, but f is only
+	// executed, if active is true, once the func is reached in the closing order.
+	// This skips the teardown of lazily initialized resources, which have never
+	// been used, e.g. a connection that has never been dialed.
+	// See Close() for its position in the closing order.
+	OnCloseIf(active *atomic.Bool, f CloseFunc)
+
+	// Manage pairs the startup of a resource with its shutdown. It calls start
+	// immediately and adds stop to the closer like OnClose, only if start succeeded.
+	// The error of start is returned. Returns ErrClosing without calling start,
+	// if the closer is already closing. If the closer starts closing while start
+	// is running, stop is called immediately and its error is joined with ErrClosing.
+	// See Close() for the position of stop in the closing order.
+	Manage(start func() error, stop CloseFunc) error
+
+	// OnCloseFinal adds the given FinalFuncs to the closer.
+	// They are executed in LIFO order after all OnClose funcs and receive all
+	// errors of the closing order so far, including the errors of the children,
+	// of previously executed final funcs and the errors passed to CloseWithErr.
+	// Their errors are joined with the closer's other errors.
+	// In fail fast mode, they are executed as well, but their errors are
+	// dropped after the first error, see WithFailFast().
+	// See Close() for their position in the closing order.
+	OnCloseFinal(f ...FinalFunc)
+
+	// OnCloseLast adds the given CloseFuncs to the closer, which are executed after
+	// all other funcs of the closing order, including the OnClose and OnCloseFinal funcs.
+	// This allows to flush or close a logger, after all other funcs have logged.
+	// Like OnClose funcs, they are executed in LIFO order among each other, hence
+	// the first registered func runs dead last. They are executed in fail fast mode
+	// as well, see WithFailFast(). Their errors are joined with the closer's other
+	// errors, but are not passed to the OnCloseFinal funcs.
+	// See Close() for their position in the closing order.
+	OnCloseLast(f ...CloseFunc)
+
 	// OnClosing adds the given CloseFuncs to the closer.
 	// Their errors are joined with the closer's other errors.
 	// Closing functions are called in LIFO order.
@@ -180,10 +735,147 @@ type Closer interface {
 	// See Close() for their position in the closing order.
 	OnClosing(f ...CloseFunc)
 
+	// OnBeforeChildrenClose adds the given CloseFuncs to the closer.
+	// They are executed in LIFO order after the closing chan has been closed
+	// and all OnClosing funcs have been executed, but strictly before the first
+	// child is closed. Use them to quiesce shared state the children depend on
+	// during their own close.
+	// Their errors are joined with the closer's other errors.
+	// See Close() for their position in the closing order.
+	OnBeforeChildrenClose(f ...CloseFunc)
+
+	// Adopt moves all children of the other closer to this closer.
+	// The children keep their one-way or two-way relationship and their order.
+	// Afterwards, the other closer has no children left.
+	// Returns ErrClosing, if either of the closers is closing, and ErrCycle,
+	// if the other closer is this closer or one of its ancestors.
+	// The other closer must have been created by this package.
+	Adopt(other Closer) error
+
+	// State returns the current lifecycle state of the closer.
+	State() State
+
+	// NumChildren returns the number of children of the closer.
+	NumChildren() int
+
+	// ForEachChild calls fn for each direct child of the closer, until fn returns false.
+	// The children are snapshotted before fn is called, so fn may use the closer
+	// and its children, e.g. to close them.
+	ForEachChild(fn func(child Closer) bool)
+
+	// Children returns the current direct children of the closer like NumChildren().
+	// The returned slice is a copy, hence it is not affected by children added or
+	// removed later and may be modified by the caller. This allows to orchestrate
+	// the close of each child individually, e.g. with CloseCtx().
+	Children() []Closer
+
+	// PendingWaits returns the current counter of the closer's wait group.
+	PendingWaits() int
+
+	// WouldBlock returns true, if Close() would likely block, because the wait group
+	// has pending entries or the closer has open children, which have to be closed first.
+	// This is a best-effort hint, e.g. to decide whether to close synchronously or
+	// in a new goroutine. It is inherently racy, because the state may change
+	// right after the call, and blocking close funcs are not considered.
+	WouldBlock() bool
+
+	// ManagedGoroutines approximates the number of goroutines the closer is responsible for.
+	// This is the sum of the wait group counters of the closer and all its descendants,
+	// which includes the routines started with RunCloserRoutine() and RunEvery()
+	// as well as the calls to CloserAddWait() and BlockCloser(). The value decreases
+	// as the routines call CloserDone().
+	// Goroutines unknown to the closers are not included.
+	ManagedGoroutines() int
+
+	// CountOpen returns the number of closers in the subtree of the closer,
+	// including the closer itself, which are not yet closed. Closing closers
+	// are counted until they are closed. With one child closer per connection,
+	// this yields the number of live connections without a separate counter.
+	CountOpen() int
+
+	// CloseDuration returns how long the closer took to close, measured from
+	// the start of the closing state until the closed state.
+	// While closing, the duration up to now is returned.
+	// Returns zero, if the closer is not yet closing.
+	CloseDuration() time.Duration
+
+	// ResourceReport returns the number of registered, cleanly closed and failed
+	// io.Closers of OnCloseCloser() by their type, see ResourceType(). This answers,
+	// whether all resources of a type closed cleanly after the close.
+	// Returns nil, if the closer has been created without WithResourceAccounting().
+	ResourceReport() map[string]ResourceStat
+
+	// Stats returns a snapshot of the closer's state, its number of children,
+	// OnClose funcs and pending waits, its close duration, closed time and name.
+	// In contrast to calling the individual accessors, all values are captured
+	// at the same moment and are consistent with each other.
+	Stats() Stats
+
+	// MarshalJSON implements the json.Marshaler interface. It encodes a snapshot
+	// of the closer as {"name":..,"state":..,"children":N,"pendingWaits":N,"closedAt":..},
+	// see Stats(). The closedAt field is omitted, if the closer is not yet closed.
+	// This allows to log a closer as a single field with a structured JSON logger.
+	MarshalJSON() ([]byte, error)
+
+	// OpenLeaves returns all closers of the tree starting at this closer,
+	// which are not closed and have no children that are not closed.
+	// These are the closers that currently keep the tree from closing,
+	// e.g. because they wait for their wait group or execute close funcs.
+	OpenLeaves() []Closer
+
+	// BlockingReasons returns human-readable reasons, why the current Close() has
+	// not finished yet, e.g. "waiting on wait group (3 pending)", "child 'db' still
+	// closing" or "in close func #2 (main.cleanup)". The reasons describe the current
+	// step of the closing order and the children, which are still closing.
+	// Unnamed children are identified by their position. This is intended to
+	// diagnose a stuck shutdown and may be called from any goroutine.
+	// Returns nil, if the closer is not closing or already closed.
+	BlockingReasons() []string
+
+	// CloseProgress returns the number of finished and total steps of the closing order.
+	// The steps are the OnClosing funcs, the children and the OnClose funcs.
+	// Before the closer is closing, done is zero and total the number of currently
+	// registered steps. During Close(), the steps are fixed and done increases
+	// as the closing order progresses.
+	// Once closed, done equals total.
+	CloseProgress() (done, total int)
+
+	// ClosePlan returns the steps that Close() would execute in their order,
+	// without executing any of them. The OnClosing and OnClose funcs are named
+	// after their function, the children after their closer name.
+	ClosePlan() []PlanStep
+
+	// FuncCounts returns the number of registered funcs per phase of the closing
+	// order, see ClosePlan(). It contains an entry for each of PhaseClosing,
+	// PhaseBeforeChildren, PhaseClose, PhaseFinal and PhaseLast. The funcs of Phase() count
+	// as PhaseClose. The closing funcs executed by BeginClosing() are not counted.
+	FuncCounts() map[Phase]int
+
+	// Labels returns a copy of the labels of the closer, see WithLabels().
+	Labels() map[string]string
+
+	// Name returns the name of the closer, see WithName() and SetName().
+	Name() string
+
+	// SetName replaces the name of the closer, e.g. once the identity of a peer
+	// is known after a handshake. The new name is used by all subsequent
+	// outputs, like Stats(), MarshalJSON(), ClosePlan() and CloseTree().
+	SetName(name string)
+
+	// ID returns the unique identifier of the closer, assigned at its creation.
+	// Unlike the name, the ID is unique among all closers of the process and
+	// therefore suitable as map key or for logging.
+	ID() uint64
+
 	// CloserError returns the joined error of this closer once it has fully closed.
 	// If there was no error or the closer is not yet closed, nil is returned.
 	CloserError() error
 
+	// SevereError returns the CloserError without the benign errors, see Benign().
+	// This allows to alert only on severe failures, whereas the CloserError
+	// still contains all errors for debugging.
+	SevereError() error
+
 	// CloserWait waits for the closer to close and returns the CloserError if present.
 	// Use the context to cancel the blocking wait.
 	CloserWait(ctx context.Context) error
@@ -192,6 +884,28 @@ type Closer interface {
 	// If the context is canceled, the context error will be send over the channel.
 	CloserWaitChan(ctx context.Context) <-chan error
 
+	// AwaitChildrenClosed blocks, until all current children of the closer are closed,
+	// without closing the closer itself. Children added during the wait are not awaited.
+	// Returns the context's error, if the context is done first.
+	AwaitChildrenClosed(ctx context.Context) error
+
+	// ChildByName returns the first direct child with the given name.
+	// Names are not guaranteed to be unique, see WithName() and SetName().
+	// Returns false, if the closer currently has no child with the name.
+	// Note that closed one-way children are removed from their parent.
+	ChildByName(name string) (Closer, bool)
+
+	// IsDescendantOf returns true, if the ancestor is a parent of this closer or
+	// of one of its ancestors. A closer is not a descendant of itself.
+	// The result reflects the tree at the time of the call, see Adopt().
+	IsDescendantOf(ancestor Closer) bool
+
+	// WaitChild waits for the first child with the given name to close and returns
+	// its CloserError if present. Use the context to cancel the blocking wait.
+	// Returns ErrChildNotFound, if the closer currently has no child with the name.
+	// Note that closed one-way children are removed from their parent.
+	WaitChild(ctx context.Context, name string) error
+
 	// BlockCloser ensures that during the function execution, the closer will not reach the
 	// closed state. This is handled by calling CloserAddWait.
 	// This call will return ErrClosed, if the closer is already closed.
@@ -204,7 +918,34 @@ type Closer interface {
 	// - start a new goroutine
 	// - wait for the routine function to return
 	// - handle the error and close the closer by calling CloseWithErrAndDone
+	// A panic in the routine is recovered and handled as error wrapping ErrPanic,
+	// which contains the stack trace of the panic.
 	RunCloserRoutine(f func() error)
+
+	// RunEvery starts a closer goroutine, which calls f on every tick of a ticker
+	// with the given interval, until the closer is closing. The closer waits for
+	// the goroutine during Close(), which stops the ticker before returning.
+	// If f returns an error, the goroutine stops and closes the closer with the error.
+	// A panic in f is recovered and handled as error wrapping ErrPanic.
+	RunEvery(d time.Duration, f func() error)
+
+	// SafeGo starts a closer goroutine for a fire-and-forget routine like
+	// RunCloserRoutine(), but the closer is only closed, if f panics.
+	// The panic is recovered and the closer is closed with an error wrapping
+	// ErrPanic, which contains the stack trace of the panic. If f returns,
+	// the closer stays open and just stops waiting for the goroutine.
+	SafeGo(f func())
+
+	// SpawnWorkers starts n closer goroutines, which call worker with the closing chan
+	// of the closer as stop chan. The closer waits for all workers during Close().
+	// A returning worker does not close the closer, but a panic is recovered and
+	// closes the closer with an error wrapping ErrPanic like SafeGo().
+	SpawnWorkers(n int, worker func(stop <-chan struct{}))
+
+	// SpawnWorkersErr starts n workers like SpawnWorkers(), but a returned error
+	// closes the closer with the error like RunCloserRoutine(). A worker returning
+	// nil does not close the closer.
+	SpawnWorkersErr(n int, worker func(stop <-chan struct{}) error)
 }
 
 //######################//
@@ -215,8 +956,14 @@ const (
 	minChildrenCap = 100
 )
 
+// The last ID assigned to a closer.
+var lastID atomic.Uint64
+
 // The closer type is this package's implementation of the Closer interface.
 type closer struct {
+	// The unique identifier of the closer.
+	id uint64
+
 	// An unbuffered channel that expresses whether the
 	// closer is about to close.
 	// The channel itself gets closed to represent the closing
@@ -230,111 +977,679 @@ type closer struct {
 	// The error collected by executing the Close() func
 	// and combining all encountered errors from the close funcs as joined error.
 	closeErr error
+	// Logs a warning if garbage collected while not closed, see WithLeakDetection().
+	leakDetector *leakDetector
 
 	// Synchronises the access to the following properties.
 	mx sync.Mutex
 	// The close funcs that are executed when this closer closes.
 	closeFuncs []CloseFunc
+	// Notified once the closing chan has been closed, see OnCloseBroadcast().
+	broadcastChans []chan<- struct{}
+	// Called after the closed chan has been closed, see DoOnClosed().
+	closedFuncs []func()
+	// Called right before the closed chan is closed, see SetFinalizer().
+	finalizer func(err error)
+	// Set, once the warning of WithMaxCloseFuncs() has been logged.
+	maxCloseFuncsWarned bool
+	// The dependencies between the close funcs by their index, see OnCloseDep().
+	closeFuncDeps map[int][]int
+	// The phases of the close funcs by their index and the order
+	// of the phases, see Phase() and SetPhaseOrder().
+	closeFuncPhases map[int]string
+	phaseOrder      []string
 	// The closing funcs that are executed when this closer closes.
 	closingFuncs []CloseFunc
+	// Executed after the closing funcs, before the children are closed.
+	beforeChildrenFuncs []CloseFunc
+	// The final funcs that are executed after the close funcs.
+	finalFuncs []FinalFunc
+	// The funcs that are executed after the final funcs, see OnCloseLast().
+	lastFuncs []CloseFunc
+	// The funcs that are called when a child is added or removed.
+	childAddedFuncs   []func(child Closer)
+	childRemovedFuncs []func(child Closer)
+	// The closers that must close before the close funcs are executed.
+	closeDeps []closeDependency
 	// The parent of this closer. May be nil.
 	parent *closer
+	// The soft closing state, see BeginClosing().
+	// The channel is closed during the soft closing state and replaced on AbortClosing().
+	// The number of closing funcs executed by BeginClosing() and their errors are kept,
+	// as well as a channel that is closed once their execution finished.
+	softClosing      bool
+	softClosingChan  chan struct{}
+	softClosingFuncs int
+	softClosingErr   error
+	softClosingDone  chan struct{}
 	// The closer children that this closer spawned.
 	children []*closer
+	// The children that are closed by Close(). Kept for introspection
+	// until the closer is closed, see OpenLeaves().
+	closingChildren []*closer
+	// The children that have been closed by Close(), see CloseTree().
+	closedChildren []*closer
 	// Used to wait for external dependencies of the closer
 	// before the Close() method actually returns.
 	// Use a custom implementation, because the sync.WaitGroup Wait() method is not thread-safe.
 	waitCond  *sync.Cond
 	waitCount int64
+	// The stack traces of the pending wait group call sites.
+	// Only recorded if build with debugging mode.
+	waitStacks []waitStack
 
 	// A flag that indicates whether this closer is a two-way closer.
 	// In comparison to a standard one-way closer, which closes when
 	// its parent closes, a two-way closer closes also its parent, when
-	// it itself gets closed.
+	// it itself gets closed. See SetTwoWay().
 	twoWay bool
 
+	// Decides whether a two-way closer closes its parent. May be nil.
+	// See CloserTwoWayIf().
+	twoWayIf func(child Closer) bool
+
+	// A flag that indicates whether the two-way relationship to the children
+	// is suspended. See SuspendTwoWay().
+	twoWaySuspended bool
+
 	// The index of this closer in its parent's children slice.
 	// Needed to efficiently remove the closer from its parent.
 	parentIndex int
+
+	// The options this closer has been created with.
+	opts options
+
+	// Closes the closer once its deadline passed. May be nil.
+	deadlineTimer *time.Timer
+	// The deadline of the timer. Zero, if no deadline is set.
+	deadline time.Time
+	// The effective deadline of the close, see ClosingDeadline(). Zero, if unbounded.
+	closingDeadline time.Time
+	// Bounds the closing order of Close(), see SetCloseTimeout().
+	closeTimeout time.Duration
+	// Bounds the wait for the wait group, see CloseWithWaitTimeout().
+	waitTimeout time.Duration
+	// The retries of failed close funcs, see CloseWithRetry().
+	closeAttempts int
+	closeBackoff  time.Duration
+	// The shared contexts of WaitGroupContext() and ClosedContext(). May be nil.
+	closingCtx    context.Context
+	closingCancel context.CancelCauseFunc
+	closedCtx     context.Context
+	closedCancel  context.CancelCauseFunc
+
+	// The reason for the close. See CloseWithReason().
+	closeReason string
+
+	// True, if the close has been initiated by the parent. See ClosedByParent().
+	closedByParent bool
+	// What initiated the close. See InitiatedBy().
+	initiator initiator
+
+	// The closers linked to this closer. See Link().
+	links []Closer
+
+	// The context of the close, passed to the funcs of OnCloseCtx().
+	closeCtx context.Context
+
+	// The errors of this closer without its children's errors, see CloseTree().
+	ownErr error
+
+	// The errors of AppendCloseErr(), which are joined with the close error.
+	appendedErrs []error
+	// The first error of the closer, see CloseAndReturnFirst().
+	firstErr error
+
+	// The points in time when the closer started closing and when it was closed.
+	closingAt time.Time
+	closedAt  time.Time
+
+	// The progress of the closing order. See CloseProgress().
+	closeStepsDone  atomic.Int64
+	closeStepsTotal int
+
+	// Whether the results of the closing steps are collected and the
+	// collected results. See CloseAndCollect().
+	collectSteps bool
+	stepResults  []StepResult
+
+	// The io.Closers registered with OnCloseCloser() by type,
+	// if resource accounting is enabled. See ResourceReport().
+	resourceStats map[string]*ResourceStat
+
+	// The current step of the closing order. See BlockingReasons().
+	closeStep string
+
+	// The pause state and its chans, which are created on demand. See Pause().
+	paused      bool
+	pausedChan  chan struct{}
+	resumedChan chan struct{}
+
+	// The ID of the goroutine executing the closing order or zero,
+	// if the closing order is not running. See checkReentrant().
+	closeOrderGoroutine atomic.Uint64
+}
+
+// A waitStack is a pending call site of the wait group, see PendingWaitStacks().
+type waitStack struct {
+	trace string
+	count int
 }
 
-// New creates a new closer.
-func New() Closer {
-	return newCloser(3)
+// A closeDependency is a closer registered with CloseAfter().
+type closeDependency struct {
+	c       Closer
+	timeout time.Duration
+}
+
+// New creates a new closer configured with the given options.
+func New(opts ...Option) Closer {
+	return newCloser(3, opts...)
 }
 
 // Implements the Closer interface.
 func (c *closer) Close() error {
+	return c.closeBy(initiator{origin: OriginDirect})
+}
+
+// closeBy implements Close() for the given initiator of the close.
+func (c *closer) closeBy(init initiator) error {
+	c.mx.Lock()
+	timeout := c.closeTimeout
+	c.mx.Unlock()
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return c.close(ctx, init)
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseAndReturnFirst() error {
+	_ = c.Close()
+	return c.getFirstErr()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseBottomUp() error {
+	return c.closeBottomUp(initiator{origin: OriginDirect})
+}
+
+// closeBottomUp implements CloseBottomUp() for the given initiator of the close.
+func (c *closer) closeBottomUp(init initiator) error {
+	c.mx.Lock()
+	if c.IsClosing() {
+		c.mx.Unlock()
+		return c.close(context.Background(), init)
+	}
+	children := c.children
+	c.children = nil
+	// Detach the children, so that they neither close nor remove themselves from this closer.
+	for _, child := range children {
+		child.mx.Lock()
+		child.parent = nil
+		child.mx.Unlock()
+	}
+	c.mx.Unlock()
+
+	var errs []error
+	for _, child := range children {
+		errs = append(errs, child.closeBottomUp(byParent(c)))
+	}
+	return errors.Join(append(errs, c.close(context.Background(), init))...)
+}
+
+// Implements the Closer interface.
+func (c *closer) ClosingDeadline() (time.Time, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.IsClosing() || c.closingDeadline.IsZero() {
+		return time.Time{}, false
+	}
+	return c.closingDeadline, true
+}
+
+// closingDeadline returns the earliest of the context's deadline and the given
+// deadline, if it is after the closing time. Returns zero, if both are unbounded.
+func closingDeadline(ctx context.Context, deadline, closingAt time.Time) time.Time {
+	d, _ := ctx.Deadline()
+	if deadline.After(closingAt) && (d.IsZero() || deadline.Before(d)) {
+		d = deadline
+	}
+	return d
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseWithWaitTimeout(d time.Duration) error {
+	c.mx.Lock()
+	if !c.IsClosing() {
+		c.waitTimeout = d
+	}
+	c.mx.Unlock()
+
+	return c.Close()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseWithRetry(attempts int, backoff time.Duration) error {
+	c.mx.Lock()
+	if !c.IsClosing() {
+		c.closeAttempts = attempts
+		c.closeBackoff = backoff
+	}
+	c.mx.Unlock()
+
+	return c.Close()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseCtx(ctx context.Context) error {
+	return c.close(ctx, initiator{origin: OriginDirect})
+}
+
+// close implements Close() and CloseCtx().
+// The context bounds the waits of the closing order.
+// The initiator is only recorded, if this call starts the close.
+func (c *closer) close(ctx context.Context, init initiator) error {
 	// Close the closing channel to signal that this closer is about to close now.
 	// Do this in a locked context and release as soon as the channel is closed.
 	// If another close call is handling this context, then wait for it to exit before returning the error.
 	c.mx.Lock()
 	if c.IsClosing() {
 		c.mx.Unlock()
-		<-c.closedChan
-		return c.closeErr
+		if init.origin == OriginDirect {
+			c.checkReentrant()
+		}
+		select {
+		case <-c.closedChan:
+			return c.closeErr
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 	close(c.closingChan)
+	c.broadcastClosing()
+	c.closedByParent = init.origin == OriginParent
+	if init.origin == OriginParent && init.by == nil && c.parent != nil {
+		init.by = c.parent
+	}
+	c.initiator = init
+	if init.reason != "" && c.closeReason == "" {
+		c.closeReason = init.reason
+	}
+	links := c.links
+	c.links = nil
+	c.closeCtx = ctx
 	// Copy the internal variables to local variables. Otherwise direct access could cause a race.
+	// Skip the closing funcs that have already been executed by BeginClosing().
 	var (
-		closingFuncs = c.closingFuncs
-		closeFuncs   = c.closeFuncs
-		children     = c.children
+		closingFuncs        = c.closingFuncs[c.softClosingFuncs:]
+		beforeChildrenFuncs = c.beforeChildrenFuncs
+		closeFuncs          = c.closeFuncs
+		closeFuncDeps       = c.closeFuncDeps
+		closeFuncPhases     = c.closeFuncPhases
+		phaseOrder          = c.phaseOrder
+		finalFuncs          = c.finalFuncs
+		lastFuncs           = c.lastFuncs
+		children            = c.children
+		closeDeps           = c.closeDeps
+		softClosingDone     = c.softClosingDone
+		waitTimeout         = c.waitTimeout
+		closeAttempts       = c.closeAttempts
+		closeBackoff        = c.closeBackoff
+		collectSteps        = c.collectSteps
 	)
+	if !c.softClosing {
+		close(c.softClosingChan)
+	}
 	c.closingFuncs = nil
+	c.beforeChildrenFuncs = nil
 	c.closeFuncs = nil
+	c.closeFuncDeps = nil
+	c.closeFuncPhases = nil
+	c.finalFuncs = nil
+	c.lastFuncs = nil
 	c.children = nil
+	c.closingChildren = children
+	c.closeDeps = nil
+	c.closeStepsTotal = len(closingFuncs) + len(beforeChildrenFuncs) + len(children) + len(closeFuncs) + len(finalFuncs) + len(lastFuncs)
+	c.closingAt = time.Now()
+	c.closingDeadline = closingDeadline(ctx, c.deadline, c.closingAt)
+	if c.closingCancel != nil {
+		c.closingCancel(c.closeCause())
+	}
+	c.setDeadline(time.Time{})
 	c.mx.Unlock()
 
+	// Close the linked closers without waiting for them.
+	c.closeLinks(links)
+
+	// Detect reentrant closes from within the funcs of the closing order.
+	c.closeOrderGoroutine.Store(goroutineID())
+
+	// Trace the closing order, if a tracer is set. The traced context is passed
+	// to the funcs of OnCloseCtx() and the children.
+	var (
+		tracer   = c.opts.tracer
+		endClose func(err error)
+	)
+	if tracer != nil {
+		ctx, endClose = tracer.StartClose(ctx, c)
+		c.mx.Lock()
+		c.closeCtx = ctx
+		c.mx.Unlock()
+	}
+
 	// We are in an unlocked state. Do not use c.closeErr directly.
-	var closeErrors error
+	// The errors of the children are only part of closeErrs.
+	var (
+		closeErrs, ownErrs []error
+		firstErr           error
+	)
+	addErr := func(err error) {
+		if err != nil {
+			closeErrs = append(closeErrs, err)
+			ownErrs = append(ownErrs, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	// Wait for the closing funcs executed by BeginClosing() and take over their errors.
+	if softClosingDone != nil {
+		c.setCloseStep("waiting on the closing funcs of BeginClosing()")
+		<-softClosingDone
+		c.mx.Lock()
+		addErr(c.softClosingErr)
+		c.mx.Unlock()
+	}
+
+	// Run a step of the closing order. Trace the step and record its outcome,
+	// if requested by CloseAndCollect().
+	var (
+		steps      []StepResult
+		phaseSteps = make(map[Phase]int)
+	)
+	run := func(phase Phase, f any, call func() error) error {
+		var (
+			start   = time.Now()
+			name    = funcName(f)
+			endStep func(err error)
+		)
+		phaseSteps[phase]++
+		c.setCloseStep(fmt.Sprintf("in %s func #%d (%s)", phase, phaseSteps[phase], name))
+		if tracer != nil {
+			endStep = tracer.StartStep(ctx, PlanStep{Phase: phase, Name: name})
+		}
+		err := call()
+		if endStep != nil {
+			endStep(err)
+		}
+		if collectSteps {
+			steps = append(steps, StepResult{Phase: phase, Name: name, Err: err, Duration: time.Since(start)})
+		}
+		return err
+	}
+
+	// In fail fast mode, the first error stops the execution of all remaining funcs.
+	failed := func() bool {
+		return c.opts.failFast && len(closeErrs) > 0
+	}
 
 	// Execute all closing funcs of this closer in LIFO order.
-	for i := len(closingFuncs) - 1; i >= 0; i-- {
-		closeErrors = errors.Join(closeErrors, closingFuncs[i]())
+	for i := len(closingFuncs) - 1; i >= 0 && !failed(); i-- {
+		f := closingFuncs[i]
+		addErr(run(PhaseClosing, f, func() error { return callCloseFunc(f) }))
+		c.closeStepsDone.Add(1)
 	}
 
-	// Close all children and join their errors.
-	for _, child := range children {
-		closeErrors = errors.Join(closeErrors, child.Close())
+	// Execute all before children funcs of this closer in LIFO order.
+	for i := len(beforeChildrenFuncs) - 1; i >= 0 && !failed(); i-- {
+		f := beforeChildrenFuncs[i]
+		addErr(run(PhaseBeforeChildren, f, func() error { return callCloseFunc(f) }))
+		c.closeStepsDone.Add(1)
+	}
+
+	// Close all children and join their errors in the order of the children.
+	// The children still closing are reported by BlockingReasons().
+	c.setCloseStep("")
+	childErrs := c.closeChildren(ctx, children)
+	for i, child := range children {
+		if collectSteps {
+			steps = append(steps, StepResult{Phase: PhaseChildren, Name: child.Name(), Err: childErrs[i], Duration: child.CloseDuration()})
+		}
+		if !failed() && childErrs[i] != nil {
+			closeErrs = append(closeErrs, childErrs[i])
+			if firstErr == nil {
+				firstErr = child.getFirstErr()
+			}
+		}
 	}
 
 	// Wait, until all dependencies of this closer have closed.
+	// Wake up the wait, if the context is done or the wait timeout expired.
+	var (
+		waitDone     = make(chan struct{})
+		waitTimer    <-chan time.Time
+		waitTimedOut bool // Protected by the mutex.
+	)
+	if waitTimeout > 0 {
+		t := time.NewTimer(waitTimeout)
+		defer t.Stop()
+		waitTimer = t.C
+	}
+	if ctx.Done() != nil || waitTimer != nil {
+		go func() {
+			select {
+			case <-waitDone:
+			case <-ctx.Done():
+				c.mx.Lock()
+				c.waitCond.Broadcast()
+				c.mx.Unlock()
+			case <-waitTimer:
+				c.mx.Lock()
+				waitTimedOut = true
+				c.waitCond.Broadcast()
+				c.mx.Unlock()
+			}
+		}()
+	}
 	c.mx.Lock()
-	for c.waitCount > 0 {
+	c.closeStep = waitGroupStep
+	for c.waitCount > 0 && ctx.Err() == nil && !waitTimedOut {
 		c.waitCond.Wait()
 	}
+	if c.waitCount > 0 && waitTimedOut {
+		addErr(ErrWaitTimeout)
+	}
 	c.mx.Unlock()
+	close(waitDone)
+
+	// Wait, until all closers this closer depends on have closed.
+	// A dependency in another branch of a closing tree would only be closed
+	// after this closer. Start closing its branch to prevent waiting in vain.
+	for _, d := range closeDeps {
+		if dc, ok := d.c.(*closer); ok {
+			if branch := c.dependencyBranch(dc); branch != nil {
+				go branch.close(ctx, initiator{origin: OriginParent})
+			}
+		}
+	}
+	for _, d := range closeDeps {
+		c.setCloseStep(fmt.Sprintf("waiting on close dependency '%s'", d.c.Name()))
+		t := time.NewTimer(d.timeout)
+		select {
+		case <-d.c.ClosedChan():
+		case <-t.C:
+			addErr(ErrDependencyTimeout)
+		case <-ctx.Done():
+		}
+		t.Stop()
+	}
+	addErr(ctx.Err())
 
 	// Execute all close funcs of this closer in LIFO order.
-	for i := len(closeFuncs) - 1; i >= 0; i-- {
-		closeErrors = errors.Join(closeErrors, closeFuncs[i]())
+	// The order respects the dependencies of OnCloseDep.
+	// The phases of Phase() take precedence over the dependencies.
+	order, cycle := orderCloseFuncs(len(closeFuncs), closeFuncDeps)
+	order = orderPhases(order, closeFuncPhases, phaseOrder)
+	if cycle {
+		addErr(ErrCloseFuncCycle)
+	}
+	for _, i := range order {
+		if failed() {
+			break
+		}
+		f := closeFuncs[i]
+		addErr(run(PhaseClose, f, func() error { return callCloseFuncRetry(ctx, f, closeAttempts, closeBackoff) }))
+		c.closeStepsDone.Add(1)
+	}
+
+	// Execute all final funcs of this closer in LIFO order.
+	// Each receives all errors so far, including the ones passed to CloseWithErr.
+	for i := len(finalFuncs) - 1; i >= 0; i-- {
+		c.mx.Lock()
+		errs := closeErrs
+		if c.closeErr != nil {
+			errs = append([]error{c.closeErr}, closeErrs...)
+		}
+		c.mx.Unlock()
+
+		f := finalFuncs[i]
+		err := run(PhaseFinal, f, func() error {
+			return callCloseFunc(func() error {
+				return f(append([]error(nil), errs...))
+			})
+		})
+		if !failed() {
+			addErr(err)
+		}
+		c.closeStepsDone.Add(1)
+	}
+
+	// Execute all last funcs of this closer in LIFO order.
+	// Like the final funcs, they are executed in fail fast mode as well.
+	for i := len(lastFuncs) - 1; i >= 0; i-- {
+		f := lastFuncs[i]
+		err := run(PhaseLast, f, func() error { return callCloseFunc(f) })
+		if !failed() {
+			addErr(err)
+		}
+		c.closeStepsDone.Add(1)
 	}
 
+	c.closeOrderGoroutine.Store(0)
+
 	// Close the closed channel to signal that this closer is closed now.
 	// Finally merge the errors. Do this in a locked context.
 	c.mx.Lock()
-	c.closeErr = errors.Join(c.closeErr, closeErrors)
+	// Errors passed before the close precede the errors of the closing order,
+	// errors passed during the close follow them.
+	if c.firstErr == nil {
+		c.firstErr = firstErr
+	}
+	if c.firstErr == nil {
+		c.firstErr = c.closeErr
+	}
+	c.ownErr = errors.Join(c.closeErr, errors.Join(ownErrs...), errors.Join(c.appendedErrs...))
+	c.closeErr = errors.Join(c.closeErr, errors.Join(closeErrs...), errors.Join(c.appendedErrs...))
+	c.appendedErrs = nil
+	// Skipped steps count as done as well.
+	c.closeStepsDone.Store(int64(c.closeStepsTotal))
+	c.closedAt = time.Now()
+	c.closingChildren = nil
+	c.closeStep = ""
+	c.closedChildren = children
+	c.stepResults = steps
+	if c.finalizer != nil {
+		if err := callFinalizer(c.finalizer, c.closeErr); err != nil {
+			c.ownErr = errors.Join(c.ownErr, err)
+			c.closeErr = errors.Join(c.closeErr, err)
+		}
+		c.finalizer = nil
+	}
 	close(c.closedChan)
+	if c.closedCancel != nil {
+		c.closedCancel(c.closeCause())
+	}
+	// The parent may change until the closer is closed, see Adopt().
+	parent := c.parent
+	twoWay := c.twoWay
+	closedFuncs := c.closedFuncs
+	c.closedFuncs = nil
 	c.mx.Unlock()
 
+	// Do not block the close with the closed funcs.
+	for _, f := range closedFuncs {
+		go callClosedFunc(f)
+	}
+
+	// The close error is not modified after the closer has closed.
+	if c.opts.errorHook != nil && c.closeErr != nil {
+		c.opts.errorHook(c, c.closeErr)
+	}
+	if endClose != nil {
+		endClose(c.closeErr)
+	}
+
 	// Close the parent now as well, if this is a two way closer.
 	// Otherwise, the closer must remove its reference from its parent's children
 	// to prevent a leak.
 	// Only perform these actions, if the parent is not closing already!
-	if c.parent != nil && !c.parent.IsClosing() {
-		if c.twoWay {
+	// The check is racy, but a further propagation is harmless, because
+	// the parent executes its closing order only once.
+	if parent != nil && !parent.IsClosing() {
+		if twoWay && !parent.isTwoWaySuspended() && (c.twoWayIf == nil || c.twoWayIf(c)) {
 			// Do not wait for the parent close. This may cause a dead-lock.
 			// Traversing up the closer tree does not require that the children wait for their parents.
-			go c.parent.Close_()
+			go parent.closeBy(initiator{origin: OriginChild, by: c})
 		} else {
-			c.parent.removeChild(c)
+			parent.removeChild(c)
 		}
 	}
 
 	return c.closeErr
 }
 
+// closeChildren closes the given children and returns their errors by index.
+// The children are closed one after another, unless WithConcurrentChildClose()
+// allows to close them across multiple goroutines.
+func (c *closer) closeChildren(ctx context.Context, children []*closer) []error {
+	errs := make([]error, len(children))
+	n := c.opts.concurrentChildClose
+	if n <= 1 || len(children) <= 1 {
+		for i, child := range children {
+			errs[i] = child.close(ctx, byParent(c))
+			c.closeStepsDone.Add(1)
+		}
+		return errs
+	}
+
+	var (
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, n)
+	)
+	wg.Add(len(children))
+	for i, child := range children {
+		sem <- struct{}{}
+		go func(i int, child *closer) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = child.close(ctx, byParent(c))
+			c.closeStepsDone.Add(1)
+		}(i, child)
+	}
+	wg.Wait()
+	return errs
+}
+
 // Implements the Closer interface.
 func (c *closer) Close_() {
 	_ = c.Close()
@@ -347,80 +1662,416 @@ func (c *closer) CloseWithErr(err error) {
 }
 
 // Implements the Closer interface.
-func (c *closer) CloseWithErrAndDone(err error) {
-	c.addError(err)
-	c.CloseAndDone_()
+func (c *closer) AppendCloseErr(err error) {
+	if err == nil {
+		return
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.IsClosed() {
+		c.appendedErrs = append(c.appendedErrs, err)
+	}
 }
 
 // Implements the Closer interface.
-func (c *closer) CloseAndDone() error {
-	c.CloserDone()
+func (c *closer) CloseWithReason(reason string) error {
+	c.mx.Lock()
+	if !c.IsClosing() && c.closeReason == "" {
+		c.closeReason = reason
+	}
+	c.mx.Unlock()
+
 	return c.Close()
 }
 
 // Implements the Closer interface.
-func (c *closer) CloseAndDone_() {
-	_ = c.CloseAndDone()
-}
+func (c *closer) ClosedByParent() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
 
-// Implements the Closer interface.
-func (c *closer) CloserAddWait(delta int) {
-	c.closerAddWait(delta, true)
+	return c.closedByParent
 }
 
-func (c *closer) closerAddWait(delta int, logEnabled bool) {
+// Implements the Closer interface.
+func (c *closer) CloseReasonText() string {
 	c.mx.Lock()
 	defer c.mx.Unlock()
 
-	c.waitCount += int64(delta)
-
-	if logEnabled && c.IsClosing() {
-		// Print a debug stacktrace if build with debugging mode.
-		if debugEnabled {
-			// Use fmt instead of log for additional new line printing.
-			fmt.Fprintf(os.Stderr, "\nDEBUG: CloserAddWait called during closing state:\n%s\n\n", stacktrace(3))
-		} else {
-			log.Println("Warning: CloserAddWait called during closing state")
-		}
-	}
+	return c.closeReason
 }
 
 // Implements the Closer interface.
-func (c *closer) CloserDone() {
+func (c *closer) CloseStatus() (reason string, err error, duration time.Duration) {
 	c.mx.Lock()
 	defer c.mx.Unlock()
 
-	c.waitCount--
-	c.waitCond.Broadcast()
-
-	if c.waitCount < 0 {
-		panic("CloserDone: negative wait counter")
+	if !c.IsClosed() {
+		return "", nil, 0
 	}
+	return c.closeReason, c.closeErr, c.closeDuration()
 }
 
 // Implements the Closer interface.
-func (c *closer) CloserOneWay() Closer {
-	return c.addChild(false)
+func (c *closer) CloseWithErrAndDone(err error) {
+	c.addError(err)
+	c.CloseAndDone_()
 }
 
 // Implements the Closer interface.
-func (c *closer) CloserTwoWay() Closer {
-	return c.addChild(true)
-}
+func (c *closer) CloseAndDone() error {
+	c.CloserDone()
+	return c.Close()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseAndDone_() {
+	_ = c.CloseAndDone()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseParentOnly(err error) {
+	c.mx.Lock()
+	parent := c.parent
+	c.mx.Unlock()
+
+	if parent == nil || parent.IsClosing() {
+		return
+	}
+
+	// Do not wait for the parent close. This causes a dead-lock,
+	// if called from a routine this closer waits for.
+	parent.addError(err)
+	go parent.closeBy(initiator{origin: OriginChild, by: c})
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseExcept(keep ...Closer) error {
+	for _, k := range keep {
+		kc, ok := k.(*closer)
+		if !ok || !kc.isDescendantOf(c) {
+			continue
+		}
+		if p := kc.getParent(); p != nil && p.detachChild(kc) {
+			p.notifyChildRemoved(kc)
+		}
+	}
+	return c.Close()
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseChildren() (err error) {
+	c.mx.Lock()
+	if c.IsClosing() {
+		c.mx.Unlock()
+		return ErrClosing
+	}
+	children := c.children
+	c.children = nil
+	// Detach the children, so that they neither close nor remove themselves from this closer.
+	for _, child := range children {
+		child.mx.Lock()
+		child.parent = nil
+		child.mx.Unlock()
+	}
+	c.mx.Unlock()
+
+	c.notifyChildRemoved(children...)
+	for _, child := range children {
+		err = errors.Join(err, child.close(context.Background(), byParent(c)))
+	}
+	return
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseSiblings() (err error) {
+	p := c.getParent()
+	if p == nil {
+		return nil
+	}
+
+	p.mx.Lock()
+	if p.IsClosing() {
+		p.mx.Unlock()
+		return ErrClosing
+	}
+	var siblings, children []*closer
+	for _, child := range p.children {
+		if child == c {
+			children = append(children, child)
+		} else {
+			siblings = append(siblings, child)
+		}
+	}
+	p.children = children
+	// Detach the siblings, so that they neither close nor remove themselves from the parent.
+	for _, sibling := range siblings {
+		sibling.mx.Lock()
+		sibling.parent = nil
+		sibling.mx.Unlock()
+	}
+	p.mx.Unlock()
+
+	p.notifyChildRemoved(siblings...)
+	for _, sibling := range siblings {
+		err = errors.Join(err, sibling.close(context.Background(), byParent(p)))
+	}
+	return
+}
+
+// Serializes all CloseAfter calls to detect cycles reliably.
+var closeDepsMx sync.Mutex
+
+// Implements the Closer interface.
+func (c *closer) CloseAfter(other Closer, timeout time.Duration) error {
+	closeDepsMx.Lock()
+	defer closeDepsMx.Unlock()
+
+	if o, ok := other.(*closer); ok && (o == c || c.isDescendantOf(o) || o.dependsOn(c, map[*closer]bool{})) {
+		return ErrCycle
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.IsClosing() {
+		return ErrClosing
+	}
+	c.closeDeps = append(c.closeDeps, closeDependency{c: other, timeout: timeout})
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) CloserAddWait(delta int) {
+	c.closerAddWait(delta, true)
+}
+
+func (c *closer) closerAddWait(delta int, logEnabled bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.waitCount += int64(delta)
+
+	// Record the call site if build with debugging mode.
+	if debugEnabled {
+		c.recordWaitStack(delta, stacktrace(3))
+	}
+
+	if logEnabled && c.IsClosing() {
+		// Print a debug stacktrace if build with debugging mode.
+		if debugEnabled {
+			// Use fmt instead of log for additional new line printing.
+			fmt.Fprintf(os.Stderr, "\nDEBUG: CloserAddWait called during closing state:\n%s\n\n", stacktrace(3))
+		} else {
+			c.logPrintln("Warning: CloserAddWait called during closing state")
+		}
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) CloserDone() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// Handle a negative wait counter as configured.
+	if c.waitCount <= 0 {
+		switch c.opts.doneUnderflowPolicy {
+		case DoneUnderflowIgnore:
+			return
+		case DoneUnderflowLog:
+			msg := "Warning: CloserDone called with zero wait counter"
+			// Append a debug stacktrace if build with debugging mode.
+			if debugEnabled {
+				msg += ":\n" + stacktrace(2)
+			}
+			c.logPrintln(msg)
+			return
+		default:
+			panic("CloserDone: negative wait counter")
+		}
+	}
+
+	c.waitCount--
+	c.waitCond.Broadcast()
+
+	if debugEnabled {
+		c.recordWaitStack(-1, "")
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) PendingWaitStacks() (stacks []string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for _, ws := range c.waitStacks {
+		for i := 0; i < ws.count; i++ {
+			stacks = append(stacks, ws.trace)
+		}
+	}
+	return
+}
+
+// Implements the Closer interface.
+func (c *closer) CloserOneWay(opts ...Option) Closer {
+	return c.addChild(false, nil, opts...)
+}
+
+// Implements the Closer interface.
+func (c *closer) CloserTwoWay(opts ...Option) Closer {
+	return c.addChild(true, nil, opts...)
+}
+
+// Implements the Closer interface.
+func (c *closer) CloserTwoWayIf(predicate func(child Closer) bool, opts ...Option) Closer {
+	return c.addChild(true, predicate, opts...)
+}
+
+// Implements the Closer interface.
+func (c *closer) Clone() Closer {
+	c.mx.Lock()
+	opts := c.opts
+	opts.labels = copyLabels(c.opts.labels)
+	opts.closeTimeout = c.closeTimeout
+	c.mx.Unlock()
+
+	return newCloser(3, func(o *options) {
+		*o = opts
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) SetDeadline(t time.Time) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.IsClosing() {
+		return ErrClosing
+	}
+	c.setDeadline(t)
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) SetCloseTimeout(d time.Duration) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.IsClosing() {
+		return ErrClosing
+	}
+	c.closeTimeout = d
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) OnChildAdded(f func(child Closer)) {
+	c.mx.Lock()
+	c.childAddedFuncs = append(c.childAddedFuncs, f)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnChildRemoved(f func(child Closer)) {
+	c.mx.Lock()
+	c.childRemovedFuncs = append(c.childRemovedFuncs, f)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) SuspendTwoWay() {
+	c.mx.Lock()
+	c.twoWaySuspended = true
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) ResumeTwoWay() {
+	c.mx.Lock()
+	c.twoWaySuspended = false
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) SetTwoWay(twoWay bool) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.IsClosing() {
+		return ErrClosing
+	}
+	c.twoWay = twoWay
+	return nil
+}
 
 // Implements the Closer interface.
 func (c *closer) Context() (context.Context, context.CancelFunc) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancelCause(context.Background())
 
 	go func() {
 		select {
 		case <-c.closingChan:
-			cancel()
+			// The errors passed to the close are added before the closing chan is closed.
+			c.mx.Lock()
+			cause := c.closeCause()
+			c.mx.Unlock()
+			cancel(cause)
 		case <-ctx.Done():
 		}
 	}()
 
-	return ctx, cancel
+	return ctx, func() { cancel(nil) }
+}
+
+// Implements the Closer interface.
+func (c *closer) WaitGroupContext() context.Context {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.closingCtx == nil {
+		c.closingCtx, c.closingCancel = context.WithCancelCause(context.Background())
+		if c.IsClosing() {
+			c.closingCancel(c.closeCause())
+		}
+	}
+	return c.closingCtx
+}
+
+// Implements the Closer interface.
+func (c *closer) ClosedContext() context.Context {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.closedCtx == nil {
+		c.closedCtx, c.closedCancel = context.WithCancelCause(context.Background())
+		if c.IsClosed() {
+			c.closedCancel(c.closeCause())
+		}
+	}
+	return c.closedCtx
+}
+
+// getCloseCtx returns the context of the close. The context is set, before any
+// close func is executed. The closing funcs executed by BeginClosing() receive a
+// context that is never done.
+func (c *closer) getCloseCtx() context.Context {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.closeCtx == nil {
+		return context.Background()
+	}
+	return c.closeCtx
+}
+
+// closeCause returns the cause of the canceled contexts.
+// The mutex must be locked.
+func (c *closer) closeCause() error {
+	if c.closeErr == nil {
+		return ErrClosed
+	}
+	return c.closeErr
 }
 
 // Implements the Closer interface.
@@ -439,6 +2090,11 @@ func (c *closer) ClosingChan() <-chan struct{} {
 	return c.closingChan
 }
 
+// Implements the Closer interface.
+func (c *closer) OnStopCh() <-chan struct{} {
+	return c.closingChan
+}
+
 // Implements the Closer interface.
 func (c *closer) ClosedChan() <-chan struct{} {
 	return c.closedChan
@@ -455,27 +2111,410 @@ func (c *closer) IsClosing() bool {
 }
 
 // Implements the Closer interface.
-func (c *closer) IsClosed() bool {
-	select {
-	case <-c.closedChan:
-		return true
-	default:
-		return false
-	}
+func (c *closer) IsClosed() bool {
+	select {
+	case <-c.closedChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) IsCloseInProgress() bool {
+	return c.IsClosing() && !c.IsClosed()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnClose(f ...CloseFunc) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.checkMaxCloseFuncs(len(f))
+	c.closeFuncs = append(c.closeFuncs, f...)
+}
+
+// Implements the Closer interface.
+func (c *closer) OnClosingCtx(f ...CloseCtxFunc) {
+	for _, ff := range f {
+		ff := ff
+		c.OnClosing(func() error {
+			return ff(c.getCloseCtx())
+		})
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseCtx(f ...CloseCtxFunc) {
+	for _, ff := range f {
+		ff := ff
+		c.OnClose(func() error {
+			return ff(c.getCloseCtx())
+		})
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseBroadcast(ch chan<- struct{}) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.IsClosing() {
+		notify(ch)
+		return
+	}
+	c.broadcastChans = append(c.broadcastChans, ch)
+}
+
+// broadcastClosing notifies the channels of OnCloseBroadcast().
+// The closer's mutex must be locked.
+func (c *closer) broadcastClosing() {
+	for _, ch := range c.broadcastChans {
+		notify(ch)
+	}
+	c.broadcastChans = nil
+}
+
+// notify sends to the channel without blocking.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) DoOnClosed(f func()) {
+	c.mx.Lock()
+	if !c.IsClosed() {
+		c.closedFuncs = append(c.closedFuncs, f)
+		c.mx.Unlock()
+		return
+	}
+	c.mx.Unlock()
+
+	callClosedFunc(f)
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseErr(f func(err error)) {
+	// The close error is not modified after the closer has closed.
+	c.DoOnClosed(func() {
+		f(c.CloserError())
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseErrTo(ch chan<- error) {
+	if ch == nil {
+		return
+	}
+	c.OnCloseErr(func(err error) {
+		select {
+		case ch <- err:
+		default:
+		}
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) SetFinalizer(f func(err error)) {
+	c.mx.Lock()
+	if !c.IsClosed() {
+		c.finalizer = f
+		c.mx.Unlock()
+		return
+	}
+	c.mx.Unlock()
+
+	// The close error is not modified after the closer has closed.
+	f(c.closeErr)
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseTimeout(d time.Duration, f CloseFunc) {
+	c.OnClose(func() error {
+		// Buffered, so the goroutine does not leak after a timeout.
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- callCloseFunc(f)
+		}()
+
+		t := time.NewTimer(d)
+		defer t.Stop()
+
+		select {
+		case err := <-errChan:
+			return err
+		case <-t.C:
+			return ErrCloseFuncTimeout
+		}
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) Defer(f func()) {
+	c.OnClose(func() error {
+		f()
+		return nil
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseOnce(f CloseFunc) CloseFunc {
+	var once sync.Once
+	g := func() (err error) {
+		once.Do(func() {
+			err = f()
+		})
+		return
+	}
+	c.OnClose(g)
+	return g
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseIf(active *atomic.Bool, f CloseFunc) {
+	c.OnClose(func() error {
+		if !active.Load() {
+			return nil
+		}
+		return f()
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) Manage(start func() error, stop CloseFunc) error {
+	if c.IsClosing() {
+		return ErrClosing
+	}
+	err := start()
+	if err != nil {
+		return err
+	}
+
+	c.mx.Lock()
+	if c.IsClosing() {
+		c.mx.Unlock()
+		// The close funcs have already been copied, so stop the resource here.
+		return errors.Join(ErrClosing, callCloseFunc(stop))
+	}
+	c.checkMaxCloseFuncs(1)
+	c.closeFuncs = append(c.closeFuncs, stop)
+	c.mx.Unlock()
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseFinal(f ...FinalFunc) {
+	c.mx.Lock()
+	c.finalFuncs = append(c.finalFuncs, f...)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseLast(f ...CloseFunc) {
+	c.mx.Lock()
+	c.lastFuncs = append(c.lastFuncs, f...)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnClosing(f ...CloseFunc) {
+	c.mx.Lock()
+	c.closingFuncs = append(c.closingFuncs, f...)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) OnBeforeChildrenClose(f ...CloseFunc) {
+	c.mx.Lock()
+	c.beforeChildrenFuncs = append(c.beforeChildrenFuncs, f...)
+	c.mx.Unlock()
+}
+
+// Implements the Closer interface.
+func (c *closer) State() State {
+	if c.IsClosed() {
+		return StateClosed
+	} else if c.IsClosing() {
+		return StateClosing
+	}
+	return StateOpen
+}
+
+// Implements the Closer interface.
+func (c *closer) NumChildren() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return len(c.children)
+}
+
+// Implements the Closer interface.
+func (c *closer) ForEachChild(fn func(child Closer) bool) {
+	c.mx.Lock()
+	children := append([]*closer(nil), c.children...)
+	c.mx.Unlock()
+
+	for _, child := range children {
+		if !fn(child) {
+			return
+		}
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) Children() []Closer {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	children := make([]Closer, len(c.children))
+	for i, child := range c.children {
+		children[i] = child
+	}
+	return children
+}
+
+// Implements the Closer interface.
+func (c *closer) PendingWaits() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return int(c.waitCount)
+}
+
+// Implements the Closer interface.
+func (c *closer) WouldBlock() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.waitCount > 0 || len(c.children) > 0
+}
+
+// Implements the Closer interface.
+func (c *closer) ManagedGoroutines() int {
+	c.mx.Lock()
+	n := int(c.waitCount)
+	c.mx.Unlock()
+
+	for _, child := range c.childrenSnapshot() {
+		n += child.ManagedGoroutines()
+	}
+	return n
+}
+
+// Implements the Closer interface.
+func (c *closer) CountOpen() int {
+	if c.IsClosed() {
+		return 0
+	}
+
+	n := 1
+	for _, child := range c.childrenSnapshot() {
+		n += child.CountOpen()
+	}
+	return n
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseDuration() time.Duration {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.closeDuration()
+}
+
+// Implements the Closer interface.
+func (c *closer) OpenLeaves() []Closer {
+	leaves, _ := c.appendOpenLeaves(nil)
+	return leaves
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseProgress() (done, total int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.IsClosing() {
+		return 0, len(c.closingFuncs) - c.softClosingFuncs + len(c.beforeChildrenFuncs) + len(c.children) + len(c.closeFuncs) + len(c.finalFuncs) + len(c.lastFuncs)
+	}
+	return int(c.closeStepsDone.Load()), c.closeStepsTotal
+}
+
+// Implements the Closer interface.
+func (c *closer) Name() string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.opts.name
 }
 
 // Implements the Closer interface.
-func (c *closer) OnClose(f ...CloseFunc) {
+func (c *closer) Labels() map[string]string {
 	c.mx.Lock()
-	c.closeFuncs = append(c.closeFuncs, f...)
+	defer c.mx.Unlock()
+
+	return copyLabels(c.opts.labels)
+}
+
+// Implements the Closer interface.
+func (c *closer) SetName(name string) {
+	c.mx.Lock()
+	c.opts.name = name
 	c.mx.Unlock()
 }
 
 // Implements the Closer interface.
-func (c *closer) OnClosing(f ...CloseFunc) {
+func (c *closer) ID() uint64 {
+	return c.id
+}
+
+// Serializes all Adopt calls to prevent lock order inversions.
+var adoptMx sync.Mutex
+
+// Implements the Closer interface.
+func (c *closer) Adopt(other Closer) error {
+	o, ok := other.(*closer)
+	if !ok {
+		return ErrUnknownCloser
+	} else if o == c || c.isDescendantOf(o) {
+		return ErrCycle
+	}
+
+	adoptMx.Lock()
+	defer adoptMx.Unlock()
+
 	c.mx.Lock()
-	c.closingFuncs = append(c.closingFuncs, f...)
+	o.mx.Lock()
+
+	if c.IsClosing() || o.IsClosing() {
+		o.mx.Unlock()
+		c.mx.Unlock()
+		return ErrClosing
+	}
+
+	removed := o.children
+	adopted := make([]*closer, 0, len(removed))
+	for _, child := range removed {
+		child.mx.Lock()
+		// Closed children are about to remove themselves from their parent.
+		if !child.IsClosed() {
+			child.parent = c
+			child.parentIndex = len(c.children)
+			c.children = append(c.children, child)
+			adopted = append(adopted, child)
+		}
+		child.mx.Unlock()
+	}
+	o.children = nil
+
+	o.mx.Unlock()
 	c.mx.Unlock()
+
+	o.notifyChildRemoved(removed...)
+	c.notifyChildAdded(adopted...)
+	return nil
 }
 
 // Implements the Closer interface.
@@ -507,6 +2546,27 @@ func (c *closer) CloserWaitChan(ctx context.Context) <-chan error {
 	return waitChan
 }
 
+// Implements the Closer interface.
+func (c *closer) AwaitChildrenClosed(ctx context.Context) error {
+	for _, child := range c.childrenSnapshot() {
+		select {
+		case <-child.closedChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) WaitChild(ctx context.Context, name string) error {
+	child := c.childByName(name)
+	if child == nil {
+		return ErrChildNotFound
+	}
+	return child.CloserWait(ctx)
+}
+
 // Implements the Closer interface.
 func (c *closer) BlockCloser(f func() error) error {
 	var trace string
@@ -583,7 +2643,57 @@ func (c *closer) RunCloserRoutine(f func() error) {
 			defer close(doneChan)
 		}
 
-		c.CloseWithErrAndDone(f())
+		c.CloseWithErrAndDone(callRoutine(f))
+	}()
+}
+
+// Implements the Closer interface.
+func (c *closer) RunEvery(d time.Duration, f func() error) {
+	c.closerAddWait(1, false)
+	go func() {
+		// CloserAddWait will also add to a closed closer. Ensure we are not in a closing state.
+		if c.IsClosing() {
+			c.CloserDone()
+			return
+		}
+
+		t := time.NewTicker(d)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-c.closingChan:
+				c.CloserDone()
+				return
+			case <-t.C:
+				if err := callRoutine(f); err != nil {
+					c.CloseWithErrAndDone(err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// Implements the Closer interface.
+func (c *closer) SafeGo(f func()) {
+	c.closerAddWait(1, false)
+	go func() {
+		// CloserAddWait will also add to a closed closer. Ensure we are not in a closing state.
+		if c.IsClosing() {
+			c.CloserDone()
+			return
+		}
+
+		err := callRoutine(func() error {
+			f()
+			return nil
+		})
+		if err != nil {
+			c.CloseWithErrAndDone(err)
+			return
+		}
+		c.CloserDone()
 	}()
 }
 
@@ -591,25 +2701,52 @@ func (c *closer) RunCloserRoutine(f func() error) {
 //### Private ###//
 //###############//
 
-// newCloser creates a new closer with the given close funcs.
-func newCloser(debugSkipStacktrace int) *closer {
+// newCloser creates a new closer with the given options.
+func newCloser(debugSkipStacktrace int, opts ...Option) *closer {
 	c := &closer{
+		id:          lastID.Add(1),
 		closingChan: make(chan struct{}),
 		closedChan:  make(chan struct{}),
+
+		softClosingChan: make(chan struct{}),
 	}
 	c.waitCond = sync.NewCond(&c.mx)
+	for _, o := range opts {
+		o(&c.opts)
+	}
+	c.closeTimeout = c.opts.closeTimeout
+	if !c.opts.deadline.IsZero() {
+		c.mx.Lock()
+		c.setDeadline(c.opts.deadline)
+		c.mx.Unlock()
+	}
+
+	var trace string
+	if debugEnabled {
+		trace = stacktrace(debugSkipStacktrace)
+	}
+
+	if c.opts.interrupt {
+		c.closeOnInterrupt()
+	}
+
+	// Warn, if the closer is garbage collected without being closed.
+	if c.opts.leakDetection {
+		c.leakDetector = newLeakDetector(c, trace)
+	}
 
 	// Print a debug stacktrace if build with debugging mode.
+	// Do not reference the closer, so that it can be garbage collected.
 	if debugEnabled {
-		trace := stacktrace(debugSkipStacktrace)
+		closingChan, closedChan := c.closingChan, c.closedChan
 		go func() {
-			<-c.closingChan
+			<-closingChan
 
 			t := time.NewTimer(debugLogAfterTimeout)
 			defer t.Stop()
 
 			select {
-			case <-c.closedChan:
+			case <-closedChan:
 				return
 			case <-t.C:
 				// Use fmt instead of log for additional new line printing.
@@ -621,6 +2758,67 @@ func newCloser(debugSkipStacktrace int) *closer {
 	return c
 }
 
+// callCloseFuncRetry calls the given close func like callCloseFunc and retries it
+// after the backoff, until it succeeds, the attempts are exhausted or the context is done.
+// Returns the error of the last attempt.
+func callCloseFuncRetry(ctx context.Context, f CloseFunc, attempts int, backoff time.Duration) (err error) {
+	err = callCloseFunc(f)
+	for a := 1; err != nil && a < attempts; a++ {
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return
+		}
+		err = callCloseFunc(f)
+	}
+	return
+}
+
+// callCloseFunc calls the given close func and recovers a potential panic.
+// A recovered panic is returned as error wrapping ErrPanic.
+func callCloseFunc(f CloseFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return f()
+}
+
+// callFinalizer calls the given finalizer with the close error and recovers
+// a potential panic. A recovered panic is returned as error wrapping ErrPanic.
+func callFinalizer(f func(err error), closeErr error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	f(closeErr)
+	return nil
+}
+
+// callClosedFunc calls the given func of DoOnClosed() and recovers a potential panic.
+// The closer has already closed, hence a recovered panic is discarded.
+func callClosedFunc(f func()) {
+	defer func() {
+		_ = recover()
+	}()
+	f()
+}
+
+// callRoutine calls the given routine func and recovers a potential panic.
+// A recovered panic is returned as error wrapping ErrPanic, including the stack trace.
+func callRoutine(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v\n%s", ErrPanic, r, debug.Stack())
+		}
+	}()
+	return f()
+}
+
 func (c *closer) addError(err error) {
 	c.mx.Lock()
 	defer c.mx.Unlock()
@@ -632,16 +2830,30 @@ func (c *closer) addError(err error) {
 
 	// Join the error.
 	c.closeErr = errors.Join(c.closeErr, err)
+	if err != nil && c.firstErr == nil && !c.IsClosing() {
+		c.firstErr = err
+	}
+}
+
+// getFirstErr returns the first error of the closer, see CloseAndReturnFirst().
+func (c *closer) getFirstErr() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.firstErr
 }
 
-// addChild creates a new closer and adds it as either
+// addChild creates a new closer with the given options and adds it as either
 // a one-way or two-way child to this closer.
-func (c *closer) addChild(twoWay bool) *closer {
+// The optional twoWayIf predicate is only used by two-way children.
+func (c *closer) addChild(twoWay bool, twoWayIf func(child Closer) bool, opts ...Option) *closer {
 	// Create a new closer and set the current closer as its parent.
-	// Also set the twoWay flag.
-	child := newCloser(4)
+	// Also set the twoWay flag and predicate.
+	// The child inherits the parent's policies, unless overridden by its options.
+	child := newCloser(4, append([]Option{c.inheritedOptions()}, opts...)...)
 	child.parent = c
 	child.twoWay = twoWay
+	child.twoWayIf = twoWayIf
 
 	// Add the closer to the current closer's children.
 	c.mx.Lock()
@@ -649,18 +2861,343 @@ func (c *closer) addChild(twoWay bool) *closer {
 	c.children = append(c.children, child)
 	c.mx.Unlock()
 
+	c.notifyChildAdded(child)
 	return child
 }
 
-// removeChild removes the given child from this closer's children.
+// inheritedOptions returns an option, which applies the policies, logger, labels
+// and error hook of this closer to a child. The options are copied immediately,
+// so later changes of this closer do not apply to the child.
+func (c *closer) inheritedOptions() Option {
+	c.mx.Lock()
+	inherited := options{
+		failFast:            c.opts.failFast,
+		doneUnderflowPolicy: c.opts.doneUnderflowPolicy,
+		logger:              c.opts.logger,
+		labels:              copyLabels(c.opts.labels),
+		errorHook:           c.opts.errorHook,
+		tracer:              c.opts.tracer,
+	}
+	c.mx.Unlock()
+
+	return func(o *options) {
+		*o = inherited
+	}
+}
+
+// logPrintln logs a warning with the configured logger.
+func (c *closer) logPrintln(v ...any) {
+	if c.opts.logger != nil {
+		c.opts.logger.Println(v...)
+	} else {
+		log.Println(v...)
+	}
+}
+
+// checkMaxCloseFuncs handles the registration of n close funcs,
+// if it exceeds the limit of WithMaxCloseFuncs().
+// The closer's mutex must be locked.
+func (c *closer) checkMaxCloseFuncs(n int) {
+	max := c.opts.maxCloseFuncs
+	if max <= 0 || len(c.closeFuncs)+n <= max {
+		return
+	}
+
+	switch c.opts.maxCloseFuncsPolicy {
+	case MaxCloseFuncsLog:
+		// Warn only once to not flood the log with a leak.
+		if c.maxCloseFuncsWarned {
+			return
+		}
+		c.maxCloseFuncsWarned = true
+		msg := fmt.Sprintf("Warning: closer exceeds the maximum of %d close funcs", max)
+		// Append a debug stacktrace if build with debugging mode.
+		if debugEnabled {
+			msg += ":\n" + stacktrace(3)
+		}
+		c.logPrintln(msg)
+	default:
+		panic(fmt.Sprintf("OnClose: exceeds the maximum of %d close funcs", max))
+	}
+}
+
+// notifyChildAdded calls the OnChildAdded funcs for each of the given children.
+// The closer's mutex must not be locked.
+func (c *closer) notifyChildAdded(children ...*closer) {
+	c.mx.Lock()
+	funcs := c.childAddedFuncs
+	c.mx.Unlock()
+
+	for _, child := range children {
+		for _, f := range funcs {
+			f(child)
+		}
+	}
+}
+
+// notifyChildRemoved calls the OnChildRemoved funcs for each of the given children.
+// The closer's mutex must not be locked.
+func (c *closer) notifyChildRemoved(children ...*closer) {
+	c.mx.Lock()
+	funcs := c.childRemovedFuncs
+	c.mx.Unlock()
+
+	for _, child := range children {
+		for _, f := range funcs {
+			f(child)
+		}
+	}
+}
+
+// setDeadline replaces the deadline timer of the closer.
+// A zero time only stops the current timer.
+// The closer's mutex must be locked.
+func (c *closer) setDeadline(t time.Time) {
+	if c.deadlineTimer != nil {
+		c.deadlineTimer.Stop()
+		c.deadlineTimer = nil
+	}
+	c.deadline = t
+	if t.IsZero() {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(t), func() {
+		// The timer might have been replaced while this func was about to run.
+		c.mx.Lock()
+		current := c.deadlineTimer == timer
+		c.mx.Unlock()
+
+		if current {
+			c.CloseWithErr(ErrDeadlineExceeded)
+		}
+	})
+	c.deadlineTimer = timer
+}
+
+// isTwoWaySuspended returns true, if the two-way relationship to the children is suspended.
+func (c *closer) isTwoWaySuspended() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.twoWaySuspended
+}
+
+// closeDuration returns the close duration. See CloseDuration().
+// The closer's mutex must be locked.
+func (c *closer) closeDuration() time.Duration {
+	if c.closingAt.IsZero() {
+		return 0
+	} else if c.closedAt.IsZero() {
+		return time.Since(c.closingAt)
+	}
+	return c.closedAt.Sub(c.closingAt)
+}
+
+// dependsOn returns true, if this closer directly or transitively
+// depends on the target closer, see CloseAfter().
+func (c *closer) dependsOn(target *closer, visited map[*closer]bool) bool {
+	if visited[c] {
+		return false
+	}
+	visited[c] = true
+
+	c.mx.Lock()
+	deps := c.closeDeps
+	c.mx.Unlock()
+
+	for _, d := range deps {
+		dc, ok := d.c.(*closer)
+		if ok && (dc == target || dc.dependsOn(target, visited)) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyBranch returns the ancestor of the dependency, which is a child of the
+// lowest common ancestor of this closer and the dependency. Returns nil, if they do
+// not share an ancestor, if the common ancestor is not closing or if the branch
+// is closing already. Closing the returned branch preserves the closing order
+// within the branch, because the common ancestor would close it anyway.
+func (c *closer) dependencyBranch(dep *closer) *closer {
+	ancestors := map[*closer]bool{}
+	for p := c; p != nil; p = p.getParent() {
+		ancestors[p] = true
+	}
+
+	branch := dep
+	for p := dep.getParent(); p != nil; p = p.getParent() {
+		if ancestors[p] {
+			if p == c || !p.IsClosing() || branch.IsClosing() {
+				return nil
+			}
+			return branch
+		}
+		branch = p
+	}
+	return nil
+}
+
+// getParent returns the current parent of the closer or nil.
+func (c *closer) getParent() *closer {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.parent
+}
+
+// recordWaitStack records a wait group call site with the given delta.
+// A negative delta removes the oldest pending call sites.
+// The closer's mutex must be locked.
+func (c *closer) recordWaitStack(delta int, trace string) {
+	if delta > 0 {
+		c.waitStacks = append(c.waitStacks, waitStack{trace: trace, count: delta})
+		return
+	}
+
+	for delta < 0 && len(c.waitStacks) > 0 {
+		ws := &c.waitStacks[0]
+		n := -delta
+		if n > ws.count {
+			n = ws.count
+		}
+		ws.count -= n
+		delta += n
+		if ws.count == 0 {
+			c.waitStacks[0] = waitStack{}
+			c.waitStacks = c.waitStacks[1:]
+		}
+	}
+}
+
+// childrenSnapshot returns a copy of the closer's children.
+// While closing, the children closed by Close() are returned.
+func (c *closer) childrenSnapshot() []*closer {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.closingChildren != nil {
+		return append([]*closer(nil), c.closingChildren...)
+	}
+	return append([]*closer(nil), c.children...)
+}
+
+// appendOpenLeaves appends the open leaves of the tree starting at this closer.
+// Returns false, if this closer is closed.
+func (c *closer) appendOpenLeaves(leaves []Closer) ([]Closer, bool) {
+	if c.IsClosed() {
+		return leaves, false
+	}
+
+	var hasOpen bool
+	for _, child := range c.childrenSnapshot() {
+		var open bool
+		leaves, open = child.appendOpenLeaves(leaves)
+		hasOpen = hasOpen || open
+	}
+	if !hasOpen {
+		leaves = append(leaves, c)
+	}
+	return leaves, true
+}
+
+// Implements the Closer interface.
+func (c *closer) ChildByName(name string) (Closer, bool) {
+	child := c.childByName(name)
+	if child == nil {
+		return nil, false
+	}
+	return child, true
+}
+
+// Implements the Closer interface.
+func (c *closer) IsDescendantOf(ancestor Closer) bool {
+	a, ok := ancestor.(*closer)
+	return ok && c.isDescendantOf(a)
+}
+
+// childByName returns the first child with the given name or nil, if none is found.
+func (c *closer) childByName(name string) *closer {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for _, child := range c.children {
+		// Lock order: parent before child.
+		child.mx.Lock()
+		found := child.opts.name == name
+		child.mx.Unlock()
+		if found {
+			return child
+		}
+	}
+	return nil
+}
+
+// isDescendantOf returns true, if the given closer is an ancestor of this closer.
+func (c *closer) isDescendantOf(ancestor *closer) bool {
+	c.mx.Lock()
+	p := c.parent
+	c.mx.Unlock()
+
+	for p != nil {
+		if p == ancestor {
+			return true
+		}
+		p.mx.Lock()
+		next := p.parent
+		p.mx.Unlock()
+		p = next
+	}
+	return false
+}
+
+// removeChild removes the given child from this closer's children
+// and calls the OnChildRemoved funcs.
 // If the child can not be found, this is a no-op.
 func (c *closer) removeChild(child *closer) {
+	if c.deleteChild(child) {
+		c.notifyChildRemoved(child)
+	}
+}
+
+// deleteChild deletes the given child from this closer's children.
+// Returns false, if the child can not be found.
+func (c *closer) deleteChild(child *closer) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.deleteChildLocked(child)
+}
+
+// detachChild deletes the given child from this closer's children and
+// turns it into a root closer. Returns false, if the child can not be found.
+func (c *closer) detachChild(child *closer) bool {
 	c.mx.Lock()
 	defer c.mx.Unlock()
 
+	if !c.deleteChildLocked(child) {
+		return false
+	}
+	// Lock order: parent before child.
+	child.mx.Lock()
+	child.parent = nil
+	child.mx.Unlock()
+	return true
+}
+
+// deleteChildLocked implements deleteChild().
+// The closer's mutex must be locked.
+func (c *closer) deleteChildLocked(child *closer) bool {
 	last := len(c.children) - 1
 	if last < 0 {
-		return
+		return false
+	}
+
+	// The child might have been adopted by another closer in the meantime.
+	if child.parentIndex > last || c.children[child.parentIndex] != child {
+		return false
 	}
 
 	c.children[last].parentIndex = child.parentIndex
@@ -678,4 +3215,5 @@ func (c *closer) removeChild(child *closer) {
 		copy(children, c.children)
 		c.children = children
 	}
+	return true
 }
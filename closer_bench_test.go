@@ -28,7 +28,10 @@
 package closer_test
 
 import (
+	"context"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/desertbit/closer/v3"
 )
@@ -132,3 +135,66 @@ func benchmarkCloserOneWay1P100C10CCCloseCC(b *testing.B) {
 		err = p.Close()
 	}
 }
+
+func BenchmarkCloser_ConcurrentChildClose(b *testing.B) {
+	b.Run("1P1000C-Serial", func(b *testing.B) {
+		benchmarkCloser1P1000C(b)
+	})
+	b.Run("1P1000C-Concurrent", func(b *testing.B) {
+		benchmarkCloser1P1000C(b, closer.WithConcurrentChildClose(64))
+	})
+}
+
+func benchmarkCloser1P1000C(b *testing.B, opts ...closer.Option) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		p := closer.New(opts...)
+		for j := 0; j < 1000; j++ {
+			c := p.CloserOneWay()
+			// Simulate a blocking closing order, like flushing a connection.
+			c.OnClose(func() error {
+				time.Sleep(100 * time.Microsecond)
+				return nil
+			})
+		}
+		b.StartTimer()
+
+		err = p.Close()
+	}
+}
+
+func BenchmarkCloser_BindContext(b *testing.B) {
+	b.Run("10kC-CloseOnContextDone", func(b *testing.B) {
+		benchmarkCloserBindContext10kC(b, closer.Closer.CloseOnContextDone)
+	})
+	b.Run("10kC-BindContextPoll", func(b *testing.B) {
+		benchmarkCloserBindContext10kC(b, closer.Closer.BindContextPoll)
+	})
+}
+
+// benchmarkCloserBindContext10kC binds 10k closers to a context and reports
+// the number of goroutines started by the binding.
+func benchmarkCloserBindContext10kC(b *testing.B, bind func(closer.Closer, context.Context)) {
+	var goroutines int
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		base := runtime.NumGoroutine()
+		ctx, cancel := context.WithCancel(context.Background())
+		closers := make([]closer.Closer, 10000)
+		b.StartTimer()
+
+		for j := range closers {
+			closers[j] = closer.New()
+			bind(closers[j], ctx)
+		}
+
+		b.StopTimer()
+		goroutines = runtime.NumGoroutine() - base
+		cancel()
+		for _, c := range closers {
+			<-c.ClosedChan()
+		}
+		b.StartTimer()
+	}
+	b.ReportMetric(float64(goroutines), "goroutines")
+}
@@ -0,0 +1,95 @@
+//go:build closer_debug
+
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func addWaits(c closer.Closer, delta int) {
+	c.CloserAddWait(delta)
+}
+
+func TestCloser_PendingWaitStacks(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	r.Empty(t, c.PendingWaitStacks())
+
+	addWaits(c, 2)
+	c.CloserAddWait(1)
+
+	stacks := c.PendingWaitStacks()
+	r.Len(t, stacks, 3)
+	r.Contains(t, stacks[0], "_test.addWaits(")
+	r.Contains(t, stacks[1], "_test.addWaits(")
+	r.NotContains(t, stacks[2], "_test.addWaits(")
+	r.Contains(t, stacks[2], "TestCloser_PendingWaitStacks")
+
+	// Done calls match the oldest call sites.
+	c.CloserDone()
+	c.CloserDone()
+	stacks = c.PendingWaitStacks()
+	r.Len(t, stacks, 1)
+	r.NotContains(t, stacks[0], "_test.addWaits(")
+
+	c.CloserDone()
+	r.Empty(t, c.PendingWaitStacks())
+}
+
+func TestCloser_OnCloseNeeds(t *testing.T) {
+	t.Parallel()
+
+	var (
+		app     = closer.New()
+		db      = app.CloserOneWay(closer.WithName("db"))
+		metrics = closer.New(closer.WithName("metrics"))
+		flushed int
+	)
+	flush := func() error {
+		flushed++
+		return nil
+	}
+
+	// The needed metrics closer is still open.
+	app.OnCloseNeeds(flush, metrics)
+	// The children close before the close funcs, hence the db is already closed.
+	app.OnCloseNeeds(flush, db)
+
+	err := app.Close()
+	r.ErrorIs(t, err, closer.ErrCloseOrder)
+	r.ErrorContains(t, err, "needs 'db', which is already closed")
+	r.NotContains(t, err.Error(), "metrics")
+	r.Equal(t, 2, flushed)
+	r.NoError(t, metrics.Close())
+}
@@ -28,7 +28,9 @@
 package closer_test
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -125,6 +127,30 @@ func TestCloser_IsClosed(t *testing.T) {
 	})
 }
 
+func TestCloser_IsCloseInProgress(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+	c.OnClose(func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	r.False(t, c.IsCloseInProgress())
+
+	go c.Close_()
+	<-started
+	r.True(t, c.IsCloseInProgress())
+
+	close(release)
+	<-c.ClosedChan()
+	r.False(t, c.IsCloseInProgress())
+}
+
 func TestCloser_WaitPanic(t *testing.T) {
 	t.Parallel()
 
@@ -197,6 +223,185 @@ func TestCloserErrors(t *testing.T) {
 	r.Same(t, err, c.CloserError())
 }
 
+func TestCloser_CloseAndReturnFirst(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errPassed  = errors.New("passed")
+		errClosing = errors.New("closing")
+		errChild1  = errors.New("child 1")
+		errChild2  = errors.New("child 2")
+		errClose   = errors.New("close")
+	)
+
+	newCloser := func() closer.Closer {
+		c := closer.New()
+		c.OnClose(func() error { return errClose })
+		child := c.CloserOneWay()
+		child.OnClose(func() error { return errChild2 })
+		child.OnClose(func() error { return errChild1 })
+		return c
+	}
+
+	// The first error of the closing order in execution order.
+	c := newCloser()
+	c.OnClosing(func() error { return errClosing })
+	r.Equal(t, errClosing, c.CloseAndReturnFirst())
+	err := c.CloserError()
+	for _, e := range []error{errClosing, errChild1, errChild2, errClose} {
+		r.ErrorIs(t, err, e)
+	}
+
+	// The first error of a child.
+	c = newCloser()
+	r.Equal(t, errChild1, c.CloseAndReturnFirst())
+
+	// An error passed before the close precedes all others.
+	c = newCloser()
+	c.OnClosing(func() error { return errClosing })
+	go c.CloseWithErr(errPassed)
+	<-c.ClosedChan()
+	r.Equal(t, errPassed, c.CloseAndReturnFirst())
+
+	// Without errors.
+	r.NoError(t, closer.New().CloseAndReturnFirst())
+}
+
+func TestCloser_ClosedByParent(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	a := c.CloserOneWay()
+	b := c.CloserTwoWay()
+	r.False(t, a.ClosedByParent())
+
+	var aByParent, bByParent atomic.Bool
+	a.OnClose(func() error {
+		aByParent.Store(a.ClosedByParent())
+		return nil
+	})
+	b.OnClose(func() error {
+		bByParent.Store(b.ClosedByParent())
+		return nil
+	})
+
+	// A direct close.
+	r.NoError(t, a.Close())
+	r.False(t, aByParent.Load())
+
+	// A close propagated by the parent.
+	r.NoError(t, c.Close())
+	r.True(t, bByParent.Load())
+	r.False(t, c.ClosedByParent())
+
+	// A close by CloseChildren.
+	c = closer.New()
+	a = c.CloserOneWay()
+	r.NoError(t, c.CloseChildren())
+	r.True(t, a.ClosedByParent())
+}
+
+func TestCloser_CloseStatus(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errClose = errors.New("close")
+		c        = closer.New()
+		closing  = make(chan struct{})
+		release  = make(chan struct{})
+	)
+	c.OnClose(func() error {
+		close(closing)
+		<-release
+		return errClose
+	})
+
+	reason, err, d := c.CloseStatus()
+	r.Empty(t, reason)
+	r.NoError(t, err)
+	r.Zero(t, d)
+
+	// No partial state is reported while closing.
+	go func() { _ = c.CloseWithReason("config reload") }()
+	<-closing
+	reason, err, d = c.CloseStatus()
+	r.Empty(t, reason)
+	r.NoError(t, err)
+	r.Zero(t, d)
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	<-c.ClosedChan()
+
+	reason, err, d = c.CloseStatus()
+	r.Equal(t, "config reload", reason)
+	r.ErrorIs(t, err, errClose)
+	r.Equal(t, c.CloseDuration(), d)
+	r.GreaterOrEqual(t, d, 10*time.Millisecond)
+}
+
+func TestCloser_AppendCloseErr(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errWarn1 = errors.New("warn 1")
+		errWarn2 = errors.New("warn 2")
+		errClose = errors.New("close")
+		c        = closer.New()
+	)
+	c.OnClose(func() error { return errClose })
+	c.AppendCloseErr(errWarn1)
+	c.AppendCloseErr(nil)
+	c.AppendCloseErr(errWarn2)
+
+	// The errors do not close the closer.
+	r.False(t, c.IsClosing())
+	r.NoError(t, c.CloserError())
+
+	err := c.CloseAndReturnFirst()
+	r.ErrorIs(t, err, errClose)
+	r.NotErrorIs(t, err, errWarn1)
+	r.Equal(t, "close\nwarn 1\nwarn 2", c.CloserError().Error())
+
+	// Errors appended after the close are ignored.
+	c.AppendCloseErr(errors.New("late"))
+	r.Equal(t, "close\nwarn 1\nwarn 2", c.CloserError().Error())
+}
+
+func TestCloser_CloseWithReason(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	r.Empty(t, c.CloseReasonText())
+
+	// Only the first reason is recorded.
+	start := make(chan struct{})
+	reasons := []string{"a", "b", "c", "d"}
+	retChan := make(chan error, len(reasons))
+	for _, reason := range reasons {
+		reason := reason
+		go func() {
+			<-start
+			retChan <- c.CloseWithReason(reason)
+		}()
+	}
+	close(start)
+	for range reasons {
+		r.NoError(t, <-retChan)
+	}
+
+	reason := c.CloseReasonText()
+	r.Contains(t, reasons, reason)
+	r.NoError(t, c.CloseWithReason("after close"))
+	r.Equal(t, reason, c.CloseReasonText())
+
+	// No reason is recorded, if the closer is already closed.
+	c = closer.New()
+	c.Close_()
+	r.NoError(t, c.CloseWithReason("late"))
+	r.Empty(t, c.CloseReasonText())
+}
+
 func TestCloseErrorsRace(t *testing.T) {
 	t.Parallel()
 
@@ -248,437 +453,2468 @@ func TestCloseFuncsLIFO(t *testing.T) {
 	}
 }
 
-func TestCloser_Context(t *testing.T) {
+func TestCloser_OnBeforeChildrenClose(t *testing.T) {
 	t.Parallel()
 
-	// Test closing the closer.
-	c := closer.New()
-	ctx, _ := c.Context()
-	c.Close_()
-	time.Sleep(time.Millisecond)
-	select {
-	case <-ctx.Done():
-	case <-time.After(time.Second):
-		t.Fatal("deadlock on context")
+	var (
+		c        = closer.New()
+		child    = c.CloserOneWay()
+		quiesced atomic.Bool
+		order    []string
+	)
+	c.OnClosing(func() error {
+		r.False(t, quiesced.Load())
+		order = append(order, "closing")
+		return nil
+	})
+	c.OnBeforeChildrenClose(func() error {
+		order = append(order, "beforeChildren2")
+		return nil
+	}, func() error {
+		r.True(t, c.IsClosing())
+		r.False(t, child.IsClosing())
+		quiesced.Store(true)
+		order = append(order, "beforeChildren1")
+		return nil
+	})
+	child.OnClosing(func() error {
+		r.True(t, quiesced.Load())
+		order = append(order, "child")
+		return nil
+	})
+	c.OnClose(func() error {
+		order = append(order, "close")
+		return nil
+	})
+	c.OnCloseFinal(func([]error) error {
+		order = append(order, "final")
+		return nil
+	})
+
+	r.NoError(t, c.Close())
+	r.Equal(t, []string{"closing", "beforeChildren1", "beforeChildren2", "child", "close", "final"}, order)
+}
+
+func TestCloser_OnCloseBroadcast(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c    = closer.New()
+		subs = []chan struct{}{make(chan struct{}, 1), make(chan struct{}, 1)}
+		full = make(chan struct{}, 1)
+	)
+	for _, ch := range subs {
+		c.OnCloseBroadcast(ch)
+	}
+	// Full and unbuffered channels must not block the close.
+	full <- struct{}{}
+	c.OnCloseBroadcast(full)
+	c.OnCloseBroadcast(make(chan struct{}))
+
+	// The subscribers are notified, before the closing order starts.
+	c.OnClose(func() error {
+		for _, ch := range subs {
+			r.Len(t, ch, 1)
+		}
+		return nil
+	})
+	// The soft closing state does not notify.
+	r.NoError(t, c.BeginClosing())
+	for _, ch := range subs {
+		r.Empty(t, ch)
+	}
+
+	r.NoError(t, c.Close())
+	for _, ch := range subs {
+		<-ch
 	}
+	r.Len(t, full, 1)
+
+	// Subscribing to a closed closer notifies immediately.
+	ch := make(chan struct{}, 1)
+	c.OnCloseBroadcast(ch)
+	r.Len(t, ch, 1)
 }
 
-func TestCloser_ContextClose(t *testing.T) {
+func TestCloser_DoOnClosed(t *testing.T) {
 	t.Parallel()
 
-	// Test closing the closer through the context.
-	c := closer.New()
-	ctx, cancel := c.Context()
-	c.CloseOnContextDone(ctx)
-	time.Sleep(time.Millisecond)
-	cancel()
+	var (
+		c      = closer.New()
+		calls  atomic.Int64
+		called = make(chan struct{}, 2)
+	)
+	c.DoOnClosed(func() {
+		r.True(t, c.IsClosed())
+		calls.Add(1)
+		called <- struct{}{}
+	})
+	c.OnClose(func() error {
+		r.Zero(t, calls.Load())
+		return nil
+	})
+	r.Zero(t, calls.Load())
+
+	r.NoError(t, c.Close())
 	select {
-	case <-c.ClosedChan():
-	case <-time.After(time.Second):
-		t.Fatal("timeout")
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-called:
 	}
-}
+	r.Equal(t, int64(1), calls.Load())
 
-func TestCloser_OneWay(t *testing.T) {
-	// Simple test case.
-	t.Run("CloseFunc", testOneWayCloseFunc)
+	// A second close does not call f again.
+	r.NoError(t, c.Close())
+	r.Never(t, func() bool { return calls.Load() != 1 }, 50*time.Millisecond, 5*time.Millisecond)
 
-	// Complex test case.
-	t.Run("Routines", testOneWayRoutines)
+	// A closed closer calls f immediately.
+	c.DoOnClosed(func() { calls.Add(1) })
+	r.Equal(t, int64(2), calls.Load())
+
+	// A blocking f does not block the close.
+	c = closer.New()
+	release := make(chan struct{})
+	defer close(release)
+	c.DoOnClosed(func() { <-release })
+	r.NoError(t, c.Close())
 }
 
-func testOneWayCloseFunc(t *testing.T) {
+func TestCloser_DoOnClosedPanic(t *testing.T) {
 	t.Parallel()
 
-	// A closer chain with only one-way closers.
-	p := closer.New()
-	c1 := p.CloserOneWay()
-	c2 := c1.CloserOneWay()
+	var (
+		parent = closer.New()
+		child  = parent.CloserTwoWay()
+		called = make(chan struct{})
+	)
+	child.DoOnClosed(func() { panic("closed") })
+	child.DoOnClosed(func() { close(called) })
 
-	// Close a child and check that the parent does not close.
-	err := c2.Close()
-	r.NoError(t, err)
-	r.False(t, c1.IsClosing())
-	r.False(t, c1.IsClosed())
+	// The panic neither escapes the close, nor skips the propagation to the parent.
+	r.NoError(t, child.Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-parent.ClosedChan():
+	}
+	<-called
 
-	p.OnClosing(func() error {
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
+	// A panic is also recovered, if the closer is already closed.
+	r.NotPanics(t, func() {
+		child.DoOnClosed(func() { panic("closed") })
+	})
+}
 
-		r.False(t, c1.IsClosing())
-		r.False(t, c1.IsClosed())
-		return nil
+func TestCloser_SetFinalizer(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errClose  = errors.New("close")
+		finalized atomic.Bool
+		observed  = make(chan bool, 10)
+		c         = closer.New()
+	)
+	for i := 0; i < cap(observed); i++ {
+		go func() {
+			<-c.ClosedChan()
+			observed <- finalized.Load()
+		}()
+	}
+	c.SetFinalizer(func(err error) { t.Error("replaced finalizer called") })
+	c.SetFinalizer(func(err error) {
+		if !errors.Is(err, errClose) {
+			t.Errorf("unexpected error: %v", err)
+		}
+		finalized.Store(true)
 	})
+	c.OnClose(func() error { return errClose })
 
-	p.OnClose(func() error {
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
+	r.ErrorIs(t, c.Close(), errClose)
+	for i := 0; i < cap(observed); i++ {
+		r.True(t, <-observed)
+	}
 
-		r.True(t, c1.IsClosing())
-		r.True(t, c1.IsClosed())
-		return nil
+	// A closed closer calls the finalizer immediately.
+	called := false
+	c.SetFinalizer(func(err error) {
+		r.ErrorIs(t, err, errClose)
+		called = true
 	})
+	r.True(t, called)
 
-	// Close the parent now.
-	err = p.Close()
-	r.NoError(t, err)
-	r.True(t, p.IsClosed())
+	// A panic is joined with the close error.
+	c = closer.New()
+	c.SetFinalizer(func(error) { panic("finalizer") })
+	err := c.Close()
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.ErrorContains(t, err, "finalizer")
 }
 
-func testOneWayRoutines(t *testing.T) {
+func TestCloser_OnCloseErr(t *testing.T) {
 	t.Parallel()
 
-	// One parent with 2 direct, one-way children.
-	p := closer.New()
-	c1 := p.CloserOneWay()
-	c2 := p.CloserOneWay()
-
-	p.OnClose(func() error {
-		// Both children must be closed before the parent closes.
-		r.True(t, c1.IsClosed())
-		r.True(t, c2.IsClosed())
-		return nil
+	var (
+		errOwn   = errors.New("own")
+		errChild = errors.New("child")
+		c        = closer.New()
+		child    = c.CloserOneWay()
+		results  = make(chan error, 1)
+	)
+	child.OnClose(func() error { return errChild })
+	c.OnClose(func() error { return errOwn })
+	c.OnCloseErr(func(err error) {
+		r.True(t, c.IsClosed())
+		results <- err
 	})
 
-	// The first child has a child of its own.
-	cc1 := c1.CloserOneWay()
+	err := c.Close()
+	var result error
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case result = <-results:
+	}
+	r.Equal(t, err, result)
+	r.ErrorIs(t, result, errOwn)
+	r.ErrorIs(t, result, errChild)
 
-	c1.OnClose(func() error {
-		// The child must be closed before.
-		r.True(t, cc1.IsClosed())
-		return nil
-	})
+	// A clean close passes nil, also if the closer is already closed.
+	c = closer.New()
+	r.NoError(t, c.Close())
+	result = errOwn
+	c.OnCloseErr(func(err error) { result = err })
+	r.NoError(t, result)
+}
 
-	c1.CloserAddWait(1)
-	c2.CloserAddWait(1)
-	cc1.CloserAddWait(2) // Try two routines.
+func TestCloser_CloseErrTo(t *testing.T) {
+	t.Parallel()
 
-	// Start routines for each of the children.
-	retChan := make(chan error, 4)
-	f := func(c closer.Closer) {
-		var err error
-		select {
-		case <-c.ClosingChan():
-			err = c.CloseAndDone()
-		case <-time.After(time.Second):
-			err = errors.New("routine timed out")
-		}
-		retChan <- err
+	var (
+		errClose = errors.New("close")
+		errs     = make(chan error, 3)
+		cs       = []closer.Closer{closer.New(), closer.New(), closer.New()}
+	)
+	cs[1].OnClose(func() error { return errClose })
+	for _, c := range cs {
+		c.CloseErrTo(errs)
+		c.CloseErrTo(nil)
+	}
+	for _, c := range cs {
+		go c.Close_()
 	}
-	go f(c1)
-	go f(c2)
-	go f(cc1)
-	go f(cc1)
 
-	// Close the parent. This should close c1 and c2, before p closes.
-	// c1 should close cc1 before it closes itself.
-	err := p.Close()
-	r.NoError(t, err)
-	for i := 0; i < 4; i++ {
+	var failed int
+	for range cs {
 		select {
-		case <-time.After(time.Second):
-			t.Fatal("main timed out")
-		case err = <-retChan:
-			r.NoError(t, err)
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out")
+		case err := <-errs:
+			if err != nil {
+				r.ErrorIs(t, err, errClose)
+				failed++
+			}
 		}
 	}
-}
+	r.Equal(t, 1, failed)
 
-func TestCloser_TwoWay(t *testing.T) {
-	// Simple test case.
-	t.Run("CloseFunc", testTwoWayCloseFunc)
+	// The error is sent exactly once.
+	errs = make(chan error, 2)
+	c := closer.New()
+	c.CloseErrTo(errs)
+	r.NoError(t, c.Close())
+	r.NoError(t, c.Close())
+	r.Eventually(t, func() bool { return len(errs) == 1 }, 3*time.Second, 5*time.Millisecond)
+	r.Never(t, func() bool { return len(errs) != 1 }, 50*time.Millisecond, 5*time.Millisecond)
 
-	// Complex test case.
-	t.Run("Routines", testTwoWayRoutines)
+	// A full channel does not block the close.
+	c = closer.New()
+	c.CloseErrTo(make(chan error))
+	r.NoError(t, c.Close())
+}
+
+func TestCloser_OnCloseTimeout(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		release = make(chan struct{})
+		order   []int
+		testErr = errors.New("test")
+	)
+	defer close(release)
+
+	c.OnClose(func() error {
+		order = append(order, 0)
+		return nil
+	})
+	c.OnCloseTimeout(time.Hour, func() error {
+		order = append(order, 1)
+		return testErr
+	})
+	c.OnCloseTimeout(10*time.Millisecond, func() error {
+		<-release
+		return nil
+	})
+	c.OnClose(func() error {
+		order = append(order, 2)
+		return nil
+	})
+
+	err := c.Close()
+	r.ErrorIs(t, err, closer.ErrCloseFuncTimeout)
+	r.ErrorIs(t, err, testErr)
+	r.Equal(t, []int{2, 1, 0}, order)
+
+	// A panic is recovered.
+	c = closer.New()
+	c.OnCloseTimeout(time.Hour, func() error { panic("test") })
+	r.ErrorIs(t, c.Close(), closer.ErrPanic)
+}
+
+func TestCloser_Defer(t *testing.T) {
+	t.Parallel()
+
+	var order []int
+	func() {
+		c := closer.New()
+		defer c.Close_()
+
+		for i := 0; i < 3; i++ {
+			i := i
+			c.Defer(func() { order = append(order, i) })
+		}
+		c.OnClose(func() error {
+			order = append(order, 3)
+			return nil
+		})
+	}()
+	r.Equal(t, []int{3, 2, 1, 0}, order)
+
+	// A panic is recovered like in any other close func.
+	c := closer.New()
+	c.Defer(func() { panic("test") })
+	r.ErrorIs(t, c.Close(), closer.ErrPanic)
+}
+
+func TestCloseFuncsPanic(t *testing.T) {
+	t.Parallel()
+
+	var first, last atomic.Bool
+
+	c := closer.New()
+	c.OnClose(func() error {
+		last.Store(true)
+		return nil
+	})
+	c.OnClose(func() error {
+		panic("close func panic")
+	})
+	c.OnClose(func() error {
+		first.Store(true)
+		return nil
+	})
+
+	err := c.Close()
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.ErrorContains(t, err, "close func panic")
+	r.ErrorIs(t, c.CloserError(), closer.ErrPanic)
+	r.True(t, first.Load())
+	r.True(t, last.Load())
+	r.True(t, c.IsClosed())
+}
+
+func TestCloser_ReentrantClose(t *testing.T) {
+	t.Parallel()
+
+	const msg = "closer: reentrant Close from within a close func"
+
+	// A close within a close func panics instead of blocking forever.
+	c := closer.New()
+	c.OnClose(func() error {
+		r.PanicsWithValue(t, msg, func() { _ = c.Close() })
+		return c.CloseCtx(context.Background())
+	})
+	err := c.Close()
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.ErrorContains(t, err, msg)
+
+	// The same applies to a child closing its parent within its closing order.
+	p := closer.New()
+	child := p.CloserOneWay()
+	child.OnClosing(func() error {
+		p.Close_()
+		return nil
+	})
+	r.ErrorContains(t, p.Close(), msg)
+
+	// Concurrent closes from other goroutines are not affected.
+	c = closer.New()
+	closing := make(chan struct{})
+	release := make(chan struct{})
+	c.OnClose(func() error {
+		close(closing)
+		<-release
+		return nil
+	})
+	go c.Close_()
+	<-closing
+	done := make(chan error, 1)
+	go func() { done <- c.Close() }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	r.NoError(t, <-done)
+
+	// A close func may close another closer, which is concurrently closing.
+	var (
+		a = closer.New()
+		b = closer.New()
+	)
+	closing = make(chan struct{})
+	release = make(chan struct{})
+	b.OnClose(func() error {
+		close(closing)
+		<-release
+		return nil
+	})
+	a.OnClose(func() error {
+		time.AfterFunc(10*time.Millisecond, func() { close(release) })
+		return b.Close()
+	})
+	go b.Close_()
+	<-closing
+	r.NoError(t, a.Close())
+}
+
+func TestCloser_OnCloseOnce(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls   atomic.Int64
+		errTest = errors.New("test")
+	)
+
+	p := closer.New()
+	c := p.CloserOneWay()
+
+	// Make the same func reachable from both closers.
+	f := c.OnCloseOnce(func() error {
+		calls.Add(1)
+		return errTest
+	})
+	p.OnClose(f)
+
+	err := p.Close()
+	r.ErrorIs(t, err, errTest)
+	r.Equal(t, int64(1), calls.Load())
+	r.NoError(t, f())
+	r.Equal(t, int64(1), calls.Load())
+}
+
+func TestCloser_OnCloseIf(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errClose = errors.New("close")
+		active   atomic.Bool
+		calls    int
+	)
+	f := func() error {
+		calls++
+		return errClose
+	}
+
+	// Skipped, if never activated.
+	c := closer.New()
+	c.OnCloseIf(&active, f)
+	r.NoError(t, c.Close())
+	r.Zero(t, calls)
+
+	// The flag is checked during the close, not on registration.
+	c = closer.New()
+	c.OnCloseIf(&active, f)
+	active.Store(true)
+	r.ErrorIs(t, c.Close(), errClose)
+	r.Equal(t, 1, calls)
+}
+
+func TestCloser_Manage(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errStart = errors.New("start")
+		order    []string
+	)
+	stop := func(name string) closer.CloseFunc {
+		return func() error {
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	c := closer.New()
+	r.NoError(t, c.Manage(func() error { return nil }, stop("a")))
+	r.ErrorIs(t, c.Manage(func() error { return errStart }, stop("failed")), errStart)
+	r.NoError(t, c.Manage(func() error { return nil }, stop("b")))
+	r.Len(t, c.ClosePlan(), 2)
+
+	// Only the started resources are stopped in LIFO order.
+	r.NoError(t, c.Close())
+	r.Equal(t, []string{"b", "a"}, order)
+
+	// A closed closer does not start the resource.
+	var started bool
+	err := c.Manage(func() error {
+		started = true
+		return nil
+	}, stop("closed"))
+	r.ErrorIs(t, err, closer.ErrClosing)
+	r.False(t, started)
+	r.Equal(t, []string{"b", "a"}, order)
+
+	// A resource started, while the closer started closing, is stopped immediately.
+	c = closer.New()
+	err = c.Manage(func() error {
+		go c.Close_()
+		<-c.ClosingChan()
+		return nil
+	}, stop("racing"))
+	r.ErrorIs(t, err, closer.ErrClosing)
+	r.Equal(t, []string{"b", "a", "racing"}, order)
+}
+
+func TestCloser_OnCloseFinal(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errChild = errors.New("child")
+		errClose = errors.New("close")
+		errCause = errors.New("cause")
+		errFinal = errors.New("final")
+		seen     []error
+	)
+
+	c := closer.New()
+	c.OnCloseFinal(func(errs []error) error {
+		seen = errs
+		return nil
+	})
+	c.OnCloseFinal(func(errs []error) error {
+		return errFinal
+	})
+	c.OnClose(func() error {
+		return errClose
+	})
+	c.CloserOneWay().OnClose(func() error {
+		return errChild
+	})
+	c.CloseWithErr(errCause)
+
+	err := c.CloserError()
+	r.ErrorIs(t, err, errFinal)
+	r.Len(t, seen, 4)
+	r.ErrorIs(t, seen[0], errCause)
+	r.ErrorIs(t, seen[1], errChild)
+	r.ErrorIs(t, seen[2], errClose)
+	r.ErrorIs(t, seen[3], errFinal)
+
+	// Without errors, no errors are passed.
+	c = closer.New()
+	c.OnCloseFinal(func(errs []error) error {
+		seen = errs
+		return nil
+	})
+	r.NoError(t, c.Close())
+	r.Empty(t, seen)
+}
+
+func TestCloser_OnCloseLast(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errSync = errors.New("sync")
+		c       = closer.New(closer.WithFailFast())
+		order   []string
+	)
+	add := func(name string, err error) closer.CloseFunc {
+		return func() error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	// The registration order does not matter.
+	c.OnCloseLast(add("sync", errSync), add("flush", nil))
+	c.OnCloseFinal(func(errs []error) error {
+		r.Len(t, errs, 1)
+		order = append(order, "final")
+		return nil
+	})
+	c.OnClose(add("close 1", errors.New("close")), add("close 2", nil))
+	c.CloserOneWay().OnClose(add("child", nil))
+
+	plan := c.ClosePlan()
+	r.Len(t, plan, 6)
+	r.Equal(t, closer.PhaseFinal, plan[3].Phase)
+	r.Equal(t, closer.PhaseLast, plan[4].Phase)
+	r.Equal(t, closer.PhaseLast, plan[5].Phase)
+
+	// The last funcs are also executed in fail fast mode, but their errors are dropped.
+	err := c.Close()
+	r.Equal(t, []string{"child", "close 2", "close 1", "final", "flush", "sync"}, order)
+	r.NotErrorIs(t, err, errSync)
+
+	// Without a previous error, the errors are joined.
+	c = closer.New()
+	c.OnCloseLast(add("sync", errSync))
+	r.ErrorIs(t, c.Close(), errSync)
+}
+
+func TestCloser_ClosingDeadline(t *testing.T) {
+	t.Parallel()
+
+	var (
+		now     = time.Now()
+		earlier = now.Add(time.Hour)
+		later   = now.Add(2 * time.Hour)
+	)
+	test := func(c closer.Closer, ctxDeadline, expected time.Time) {
+		_, ok := c.ClosingDeadline()
+		r.False(t, ok)
+
+		var (
+			deadline time.Time
+			closing  bool
+		)
+		c.OnClose(func() error {
+			deadline, closing = c.ClosingDeadline()
+			return nil
+		})
+		ctx, cancel := context.WithDeadline(context.Background(), ctxDeadline)
+		defer cancel()
+		r.NoError(t, c.CloseCtx(ctx))
+		r.True(t, closing)
+		r.True(t, expected.Equal(deadline), "expected %v, got %v", expected, deadline)
+	}
+
+	// The earlier deadline is reported.
+	test(closer.New(closer.WithDeadline(earlier)), later, earlier)
+	test(closer.New(closer.WithDeadline(later)), earlier, earlier)
+
+	// The close timeout bounds the close.
+	c := closer.New(closer.WithCloseTimeout(time.Hour))
+	r.NoError(t, c.SetDeadline(later))
+	var deadline time.Time
+	c.OnClose(func() error {
+		deadline, _ = c.ClosingDeadline()
+		return nil
+	})
+	start := time.Now()
+	r.NoError(t, c.Close())
+	r.WithinDuration(t, start.Add(time.Hour), deadline, time.Second)
+
+	// Unbounded.
+	c = closer.New()
+	var ok bool
+	c.OnClose(func() error {
+		_, ok = c.ClosingDeadline()
+		return nil
+	})
+	r.NoError(t, c.Close())
+	r.False(t, ok)
+}
+
+func TestCloser_CloseWithWaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	// A routine never calls done, but all close funcs are still executed.
+	var (
+		c     = closer.New()
+		calls atomic.Int64
+	)
+	c.CloserAddWait(1)
+	for i := 0; i < 3; i++ {
+		c.OnClose(func() error {
+			// The close funcs are not bounded.
+			time.Sleep(30 * time.Millisecond)
+			calls.Add(1)
+			return nil
+		})
+	}
+	start := time.Now()
+	err := c.CloseWithWaitTimeout(20 * time.Millisecond)
+	r.ErrorIs(t, err, closer.ErrWaitTimeout)
+	r.NotErrorIs(t, err, context.DeadlineExceeded)
+	r.GreaterOrEqual(t, time.Since(start), 110*time.Millisecond)
+	r.Equal(t, int64(3), calls.Load())
+	r.True(t, c.IsClosed())
+
+	// No error, if the wait group finishes in time.
+	c = closer.New()
+	c.CloserAddWait(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		c.CloserDone()
+	}()
+	r.NoError(t, c.CloseWithWaitTimeout(3*time.Second))
+}
+
+func TestCloser_CloseWithRetry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errThrottled = errors.New("throttled")
+		errPermanent = errors.New("permanent")
+		c            = closer.New()
+		okCalls      atomic.Int64
+		flakyCalls   atomic.Int64
+		brokenCalls  atomic.Int64
+	)
+	c.OnClose(func() error {
+		okCalls.Add(1)
+		return nil
+	})
+	c.OnClose(func() error {
+		// Fail the first attempt only.
+		if flakyCalls.Add(1) == 1 {
+			return errThrottled
+		}
+		return nil
+	})
+	c.OnClose(func() error {
+		brokenCalls.Add(1)
+		return errPermanent
+	})
+
+	err := c.CloseWithRetry(3, 5*time.Millisecond)
+	r.ErrorIs(t, err, errPermanent)
+	r.NotErrorIs(t, err, errThrottled)
+	r.Equal(t, int64(1), okCalls.Load())
+	r.Equal(t, int64(2), flakyCalls.Load())
+	r.Equal(t, int64(3), brokenCalls.Load())
+
+	// Without retries, the first error is kept.
+	flakyCalls.Store(0)
+	c = closer.New()
+	c.OnClose(func() error {
+		if flakyCalls.Add(1) == 1 {
+			return errThrottled
+		}
+		return nil
+	})
+	r.ErrorIs(t, c.CloseWithRetry(1, 0), errThrottled)
+	r.Equal(t, int64(1), flakyCalls.Load())
+}
+
+func TestCloser_SetCloseTimeout(t *testing.T) {
+	t.Parallel()
+
+	// The timeout aborts the wait group.
+	c := closer.New()
+	c.CloserAddWait(1)
+	r.NoError(t, c.SetCloseTimeout(50*time.Millisecond))
+	start := time.Now()
+	r.ErrorIs(t, c.Close(), context.DeadlineExceeded)
+	r.Less(t, time.Since(start), 3*time.Second)
+	r.True(t, c.IsClosed())
+	r.ErrorIs(t, c.SetCloseTimeout(time.Second), closer.ErrClosing)
+
+	// The timeout overrides the option and a timeout <= 0 disables it.
+	c = closer.New(closer.WithCloseTimeout(50 * time.Millisecond))
+	c.CloserAddWait(1)
+	r.NoError(t, c.SetCloseTimeout(0))
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		c.CloserDone()
+	}()
+	r.NoError(t, c.Close())
+}
+
+func TestCloser_CloseCtx(t *testing.T) {
+	t.Parallel()
+
+	// An already canceled context skips the wait group.
+	var closeCalled atomic.Bool
+	c := closer.New()
+	c.CloserAddWait(1)
+	c.OnClose(func() error {
+		closeCalled.Store(true)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ErrorIs(t, c.CloseCtx(ctx), context.Canceled)
+	r.True(t, c.IsClosed())
+	r.True(t, closeCalled.Load())
+	r.ErrorIs(t, c.CloserError(), context.Canceled)
+
+	// A context canceled during the close aborts the wait of the tree.
+	c = closer.New()
+	cc := c.CloserOneWay()
+	cc.CloserAddWait(1)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.CloseCtx(ctx)
+	r.ErrorIs(t, err, context.DeadlineExceeded)
+	r.Less(t, time.Since(start), 3*time.Second)
+	r.True(t, cc.IsClosed())
+
+	// A done context does not affect a close without waits.
+	c = closer.New()
+	r.NoError(t, c.CloseCtx(context.Background()))
+
+	// Waiting for a concurrent close is canceled.
+	c = closer.New()
+	c.CloserAddWait(1)
+	go c.Close_()
+	<-c.ClosingChan()
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(t, c.CloseCtx(ctx), context.DeadlineExceeded)
+	c.CloserDone()
+	<-c.ClosedChan()
+}
+
+func TestCloser_OnCloseCtx(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	var (
+		c     = closer.New()
+		child = c.CloserOneWay()
+		ctxs  = make(chan context.Context, 2)
+	)
+	c.OnCloseCtx(func(ctx context.Context) error {
+		ctxs <- ctx
+		return nil
+	})
+	child.OnCloseCtx(func(ctx context.Context) error {
+		ctxs <- ctx
+		return nil
+	})
+
+	ctx := context.WithValue(context.Background(), key{}, "v")
+	r.NoError(t, c.CloseCtx(ctx))
+	r.Equal(t, "v", (<-ctxs).Value(key{}))
+	r.Equal(t, "v", (<-ctxs).Value(key{}))
+
+	// A context aware close func aborts, if the context cancels mid-close.
+	c = closer.New()
+	c.OnCloseCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(t, c.CloseCtx(ctx), context.DeadlineExceeded)
+
+	// Close passes a context, which is never done.
+	c = closer.New()
+	c.OnCloseCtx(func(ctx context.Context) error {
+		r.NotNil(t, ctx)
+		r.Nil(t, ctx.Done())
+		return nil
+	})
+	r.NoError(t, c.Close())
+}
+
+func TestCloser_OnClosingCtx(t *testing.T) {
+	t.Parallel()
+
+	// A closing func respects the canceled context, e.g. of a bounded deregistration.
+	var (
+		c      = closer.New()
+		closed atomic.Bool
+	)
+	c.OnClose(func() error {
+		closed.Store(true)
+		return nil
+	})
+	c.OnClosingCtx(func(ctx context.Context) error {
+		r.False(t, closed.Load())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(3 * time.Second):
+			return nil
+		}
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := c.CloseCtx(ctx)
+	r.ErrorIs(t, err, context.Canceled)
+	r.True(t, closed.Load())
+
+	// The close timeout bounds the context.
+	c = closer.New(closer.WithCloseTimeout(20 * time.Millisecond))
+	c.OnClosingCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	r.ErrorIs(t, c.Close(), context.DeadlineExceeded)
+
+	// BeginClosing passes a context, which is never done.
+	c = closer.New()
+	c.OnClosingCtx(func(ctx context.Context) error {
+		r.Nil(t, ctx.Done())
+		return nil
+	})
+	r.NoError(t, c.BeginClosing())
+	r.NoError(t, c.Close())
+}
+
+func TestCloser_Context(t *testing.T) {
+	t.Parallel()
+
+	// Test closing the closer.
+	c := closer.New()
+	ctx, _ := c.Context()
+	c.Close_()
+	time.Sleep(time.Millisecond)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deadlock on context")
+	}
+}
+
+func TestCloser_ContextCause(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test")
+	c := closer.New()
+	ctx, cancel := c.Context()
+	defer cancel()
+	c.CloseWithErr(testErr)
+	<-ctx.Done()
+	r.ErrorIs(t, ctx.Err(), context.Canceled)
+	r.ErrorIs(t, context.Cause(ctx), testErr)
+	r.ErrorIs(t, c.CloserError(), testErr)
+
+	// A close without error.
+	c = closer.New()
+	ctx, cancel = c.Context()
+	defer cancel()
+	r.NoError(t, c.Close())
+	<-ctx.Done()
+	r.ErrorIs(t, context.Cause(ctx), closer.ErrClosed)
+
+	// The cancel func does not set a cause.
+	c = closer.New()
+	ctx, cancel = c.Context()
+	cancel()
+	r.ErrorIs(t, context.Cause(ctx), context.Canceled)
+}
+
+func TestCloser_WaitGroupContext(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errOwn    = errors.New("own")
+		c         = closer.New()
+		wgCtx     = c.WaitGroupContext()
+		closedCtx = c.ClosedContext()
+	)
+	r.Equal(t, wgCtx, c.WaitGroupContext())
+	r.Equal(t, closedCtx, c.ClosedContext())
+
+	// A routine of the wait group unwinds with the context.
+	c.CloserAddWait(1)
+	go func() {
+		defer c.CloserDone()
+		<-wgCtx.Done()
+		select {
+		case <-c.ClosingChan():
+		default:
+			t.Error("closing chan not closed")
+		}
+		if closedCtx.Err() != nil {
+			t.Error("closed context canceled while closing")
+		}
+	}()
+	c.OnClose(func() error {
+		r.NoError(t, closedCtx.Err())
+		return errOwn
+	})
+
+	r.ErrorIs(t, c.Close(), errOwn)
+	r.ErrorIs(t, context.Cause(wgCtx), closer.ErrClosed)
+	<-closedCtx.Done()
+	r.ErrorIs(t, context.Cause(closedCtx), errOwn)
+
+	// Contexts of a closed closer are canceled immediately.
+	c = closer.New()
+	r.NoError(t, c.WaitGroupContext().Err())
+	r.NoError(t, c.Close())
+	r.ErrorIs(t, context.Cause(c.WaitGroupContext()), closer.ErrClosed)
+	c = closer.New()
+	r.NoError(t, c.Close())
+	r.ErrorIs(t, context.Cause(c.ClosedContext()), closer.ErrClosed)
+}
+
+func TestCloser_ContextClose(t *testing.T) {
+	t.Parallel()
+
+	// Test closing the closer through the context.
+	c := closer.New()
+	ctx, cancel := c.Context()
+	c.CloseOnContextDone(ctx)
+	time.Sleep(time.Millisecond)
+	cancel()
+	select {
+	case <-c.ClosedChan():
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestCloser_OneWay(t *testing.T) {
+	// Simple test case.
+	t.Run("CloseFunc", testOneWayCloseFunc)
+
+	// Complex test case.
+	t.Run("Routines", testOneWayRoutines)
+}
+
+func testOneWayCloseFunc(t *testing.T) {
+	t.Parallel()
+
+	// A closer chain with only one-way closers.
+	p := closer.New()
+	c1 := p.CloserOneWay()
+	c2 := c1.CloserOneWay()
+
+	// Close a child and check that the parent does not close.
+	err := c2.Close()
+	r.NoError(t, err)
+	r.False(t, c1.IsClosing())
+	r.False(t, c1.IsClosed())
+
+	p.OnClosing(func() error {
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.False(t, c1.IsClosing())
+		r.False(t, c1.IsClosed())
+		return nil
+	})
+
+	p.OnClose(func() error {
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.True(t, c1.IsClosed())
+		return nil
+	})
+
+	// Close the parent now.
+	err = p.Close()
+	r.NoError(t, err)
+	r.True(t, p.IsClosed())
+}
+
+func testOneWayRoutines(t *testing.T) {
+	t.Parallel()
+
+	// One parent with 2 direct, one-way children.
+	p := closer.New()
+	c1 := p.CloserOneWay()
+	c2 := p.CloserOneWay()
+
+	p.OnClose(func() error {
+		// Both children must be closed before the parent closes.
+		r.True(t, c1.IsClosed())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+
+	// The first child has a child of its own.
+	cc1 := c1.CloserOneWay()
+
+	c1.OnClose(func() error {
+		// The child must be closed before.
+		r.True(t, cc1.IsClosed())
+		return nil
+	})
+
+	c1.CloserAddWait(1)
+	c2.CloserAddWait(1)
+	cc1.CloserAddWait(2) // Try two routines.
+
+	// Start routines for each of the children.
+	retChan := make(chan error, 4)
+	f := func(c closer.Closer) {
+		var err error
+		select {
+		case <-c.ClosingChan():
+			err = c.CloseAndDone()
+		case <-time.After(time.Second):
+			err = errors.New("routine timed out")
+		}
+		retChan <- err
+	}
+	go f(c1)
+	go f(c2)
+	go f(cc1)
+	go f(cc1)
+
+	// Close the parent. This should close c1 and c2, before p closes.
+	// c1 should close cc1 before it closes itself.
+	err := p.Close()
+	r.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatal("main timed out")
+		case err = <-retChan:
+			r.NoError(t, err)
+		}
+	}
+}
+
+func TestCloser_TwoWay(t *testing.T) {
+	// Simple test case.
+	t.Run("CloseFunc", testTwoWayCloseFunc)
+
+	// Complex test case.
+	t.Run("Routines", testTwoWayRoutines)
 
 	// Test for asynchronous child close.
 	t.Run("ParentWaitGroup", testTwoWayParentWaitGroup)
 }
 
-func testTwoWayCloseFunc(t *testing.T) {
+func TestCloser_SetTwoWay(t *testing.T) {
+	t.Parallel()
+
+	// A downgraded child closes without closing its parent.
+	p := closer.New()
+	c := p.CloserTwoWay()
+	r.NoError(t, c.SetTwoWay(false))
+	r.NoError(t, c.Close())
+	r.False(t, p.IsClosing())
+	r.Zero(t, p.NumChildren())
+
+	// An upgraded child closes its parent.
+	c = p.CloserOneWay()
+	r.NoError(t, c.SetTwoWay(true))
+	r.NoError(t, c.Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+	r.ErrorIs(t, c.SetTwoWay(false), closer.ErrClosing)
+}
+
+func TestCloser_TwoWayIf(t *testing.T) {
+	t.Parallel()
+
+	errFailed := errors.New("failed")
+	onError := func(child closer.Closer) bool {
+		return child.CloserError() != nil
+	}
+
+	// The child closes without an error, so the parent stays open.
+	p := closer.New()
+	c := p.CloserTwoWayIf(onError)
+	r.NoError(t, c.Close())
+	r.False(t, p.IsClosing())
+	r.Zero(t, p.Stats().NumChildren)
+
+	// The child closes with an error, so the parent closes as well.
+	c = p.CloserTwoWayIf(onError)
+	c.OnClose(func() error { return errFailed })
+	r.ErrorIs(t, c.Close(), errFailed)
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+
+	// A nil predicate behaves like CloserTwoWay().
+	p = closer.New()
+	r.NoError(t, p.CloserTwoWayIf(nil).Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+}
+
+func testTwoWayCloseFunc(t *testing.T) {
+	t.Parallel()
+
+	// A closer chain with only two-way closers.
+	p := closer.New()
+	c1 := p.CloserTwoWay()
+	c2 := c1.CloserTwoWay()
+
+	p.OnClose(func() error {
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.True(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+	c1.OnClose(func() error {
+		// We closed the first-level child, at least the second-level child must be closed.
+		// The parent must not be closing yet.
+		r.False(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.False(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+	c2.OnClose(func() error {
+		// We closed the second-level child, all others must not yet be closing.
+		r.False(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.False(t, c1.IsClosing())
+		r.False(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.False(t, c2.IsClosed())
+		return nil
+	})
+
+	// Close the lowest child now.
+	err := c2.Close()
+	r.NoError(t, err)
+
+	// Wait for the parent to close. This happens in a new goroutine.
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+
+	// Repeat the test, but this time close the parent.
+	p = closer.New()
+	c1 = p.CloserTwoWay()
+	c2 = c1.CloserTwoWay()
+
+	p.OnClose(func() error {
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.True(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+	c1.OnClose(func() error {
+		// We close the child, so the parent and the other children must close first.
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.False(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+	c2.OnClose(func() error {
+		// We close the child, so the parent and the other children must close first.
+		r.True(t, p.IsClosing())
+		r.False(t, p.IsClosed())
+
+		r.True(t, c1.IsClosing())
+		r.False(t, c1.IsClosed())
+
+		r.True(t, c2.IsClosing())
+		r.False(t, c2.IsClosed())
+		return nil
+	})
+
+	// Close the parent this time.
+	err = p.Close()
+	r.NoError(t, err)
+	r.True(t, p.IsClosed())
+}
+
+func testTwoWayRoutines(t *testing.T) {
+	t.Parallel()
+
+	// One parent with 2 direct, two-way children.
+	p := closer.New()
+	c1 := p.CloserTwoWay()
+	c2 := p.CloserTwoWay()
+
+	p.OnClose(func() error {
+		// Both children must be closed
+		r.True(t, c1.IsClosed())
+		r.True(t, c2.IsClosed())
+		return nil
+	})
+
+	// This is a child of the first child. This closer
+	// will be closed.
+	cc1 := c1.CloserTwoWay()
+	cc1.OnClose(func() error {
+		r.False(t, p.IsClosed())
+		r.False(t, c1.IsClosed())
+		r.False(t, c2.IsClosed())
+		return nil
+	})
+
+	c1.OnClose(func() error {
+		r.True(t, cc1.IsClosed())
+		r.False(t, p.IsClosed())
+		r.False(t, c2.IsClosed())
+		return nil
+	})
+
+	p.CloserAddWait(1)
+	c1.CloserAddWait(1)
+	c2.CloserAddWait(1)
+
+	retChan := make(chan error, 4)
+	f := func(c closer.Closer) {
+		var err error
+		select {
+		case <-c.ClosingChan():
+			err = c.CloseAndDone()
+		case <-time.After(time.Second):
+			err = errors.New("routine timed out")
+		}
+		retChan <- err
+	}
+	go f(c1)
+	go f(c2)
+	go f(p)
+
+	// Close a child. This should close c1, c2 and p.
+	err := c1.Close()
+	r.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-time.After(time.Second):
+			t.Fatal("main timed out")
+		case err = <-retChan:
+			r.NoError(t, err)
+		}
+	}
+}
+
+func testTwoWayParentWaitGroup(t *testing.T) {
+	t.Parallel()
+
+	p := closer.New()
+	c := p.CloserTwoWay()
+
+	go func() {
+		p.CloserAddWait(1)
+		defer p.CloseAndDone_()
+
+		c.Close_()
+	}()
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+}
+
+func TestCloser_Clone(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errFirst  = errors.New("first")
+		errSecond = errors.New("second")
+		labels    = map[string]string{"service": "api"}
+		proto     = closer.New(closer.WithName("proto"), closer.WithLabels(labels), closer.WithFailFast())
+		ran       atomic.Bool
+	)
+	r.NoError(t, proto.SetCloseTimeout(50*time.Millisecond))
+	proto.OnClose(func() error {
+		ran.Store(true)
+		return nil
+	})
+	_ = proto.CloserOneWay()
+	proto.CloserAddWait(1)
+
+	c := proto.Clone()
+	r.NotEqual(t, proto.ID(), c.ID())
+	r.Equal(t, "proto", c.Name())
+	r.Equal(t, labels, c.Labels())
+
+	// The state is not copied.
+	s := c.Stats()
+	r.Zero(t, s.NumChildren)
+	r.Zero(t, s.NumCloseFuncs)
+	r.Zero(t, s.PendingWaits)
+
+	// The options apply to the clone.
+	c.OnClose(func() error { return errSecond })
+	c.OnClose(func() error { return errFirst })
+	err := c.Close()
+	r.ErrorIs(t, err, errFirst)
+	r.NotErrorIs(t, err, errSecond)
+	r.False(t, ran.Load())
+
+	c = proto.Clone()
+	c.CloserAddWait(1)
+	r.ErrorIs(t, c.Close(), context.DeadlineExceeded)
+
+	// The clone is independent of the prototype.
+	r.False(t, proto.IsClosing())
+	proto.CloserDone()
+	r.NoError(t, proto.Close())
+	r.True(t, ran.Load())
+}
+
+func TestCloser_CloseBottomUp(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mx    sync.Mutex
+		order []string
+		errCC = errors.New("cc")
+		p     = closer.New()
+		c1    = p.CloserOneWay()
+		cc    = c1.CloserTwoWay()
+		c2    = p.CloserTwoWay()
+	)
+	record := func(c closer.Closer, name string, err error) {
+		c.OnClosing(func() error {
+			mx.Lock()
+			order = append(order, name+" closing")
+			mx.Unlock()
+			return nil
+		})
+		c.OnClose(func() error {
+			// No ancestor must be closing yet.
+			if p.IsClosing() {
+				t.Error("parent closing before its descendants closed")
+			}
+			mx.Lock()
+			order = append(order, name+" close")
+			mx.Unlock()
+			return err
+		})
+	}
+	record(cc, "cc", errCC)
+	record(c1, "c1", nil)
+	record(c2, "c2", nil)
+	p.OnClose(func() error {
+		mx.Lock()
+		order = append(order, "p close")
+		mx.Unlock()
+		return nil
+	})
+
+	err := p.CloseBottomUp()
+	r.ErrorIs(t, err, errCC)
+	r.True(t, p.IsClosed())
+	r.True(t, c1.IsClosed())
+	r.True(t, cc.IsClosed())
+	r.True(t, c2.IsClosed())
+	r.Equal(t, []string{
+		"cc closing", "cc close",
+		"c1 closing", "c1 close",
+		"c2 closing", "c2 close",
+		"p close",
+	}, order)
+
+	// An already closed closer returns its error.
+	r.NoError(t, p.CloseBottomUp())
+	r.ErrorIs(t, cc.CloseBottomUp(), errCC)
+}
+
+func TestCloser_CloseChildren(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errChild = errors.New("child")
+		p        = closer.New()
+		c1       = p.CloserOneWay()
+		c2       = p.CloserTwoWay()
+		cc       = c2.CloserTwoWay()
+	)
+	p.OnClose(func() error {
+		return errors.New("must not run")
+	})
+	c1.OnClose(func() error {
+		return errChild
+	})
+
+	err := p.CloseChildren()
+	r.ErrorIs(t, err, errChild)
+	r.True(t, c1.IsClosed())
+	r.True(t, c2.IsClosed())
+	r.True(t, cc.IsClosed())
+	r.Zero(t, p.NumChildren())
+
+	// The two-way child must not close the parent.
+	time.Sleep(50 * time.Millisecond)
+	r.False(t, p.IsClosing())
+
+	// New children can be created.
+	c3 := p.CloserOneWay()
+	r.Equal(t, 1, p.NumChildren())
+	r.Error(t, p.Close())
+	r.True(t, c3.IsClosed())
+	r.ErrorIs(t, p.CloseChildren(), closer.ErrClosing)
+}
+
+func TestCloser_CloseSiblings(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errSibling = errors.New("sibling")
+		p          = closer.New()
+		c          = p.CloserTwoWay()
+		s1         = p.CloserOneWay()
+		s2         = p.CloserTwoWay()
+		ss         = s2.CloserTwoWay()
+	)
+	s1.OnClose(func() error {
+		return errSibling
+	})
+
+	err := c.CloseSiblings()
+	r.ErrorIs(t, err, errSibling)
+	r.True(t, s1.IsClosed())
+	r.True(t, s2.IsClosed())
+	r.True(t, ss.IsClosed())
+	r.Equal(t, 1, p.NumChildren())
+
+	// Neither the parent nor the closer itself must close.
+	time.Sleep(50 * time.Millisecond)
+	r.False(t, p.IsClosing())
+	r.False(t, c.IsClosing())
+
+	// No siblings left.
+	r.NoError(t, c.CloseSiblings())
+
+	// No parent.
+	r.NoError(t, p.CloseSiblings())
+
+	r.NoError(t, p.Close())
+	r.True(t, c.IsClosed())
+}
+
+func TestCloser_CloseExcept(t *testing.T) {
+	t.Parallel()
+
+	var (
+		p       = closer.New()
+		cache   = p.CloserTwoWay()
+		entry   = cache.CloserOneWay()
+		workers = p.CloserOneWay()
+		pool    = workers.CloserTwoWay()
+		worker  = workers.CloserOneWay()
+		other   = closer.New()
+	)
+
+	r.NoError(t, p.CloseExcept(cache, pool, other))
+	r.True(t, p.IsClosed())
+	r.True(t, workers.IsClosed())
+	r.True(t, worker.IsClosed())
+
+	// The kept subtrees stay open and are detached.
+	r.False(t, cache.IsClosing())
+	r.False(t, entry.IsClosing())
+	r.False(t, pool.IsClosing())
+	r.False(t, other.IsClosing())
+	r.Zero(t, p.NumChildren())
+
+	// A kept two-way child is a root closer now.
+	r.NoError(t, pool.Close())
+	origin, _ := pool.InitiatedBy()
+	r.Equal(t, closer.OriginDirect, origin)
+	r.NoError(t, cache.Close())
+	r.True(t, entry.IsClosed())
+	r.False(t, other.IsClosing())
+}
+
+func TestCloser_OnChildAddedRemoved(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mx      sync.Mutex
+		added   []uint64
+		removed []uint64
+		p       = closer.New()
+	)
+	p.OnChildAdded(func(child closer.Closer) {
+		// Must not dead-lock.
+		_ = p.NumChildren()
+		mx.Lock()
+		added = append(added, child.ID())
+		mx.Unlock()
+	})
+	p.OnChildRemoved(func(child closer.Closer) {
+		_ = p.NumChildren()
+		mx.Lock()
+		removed = append(removed, child.ID())
+		mx.Unlock()
+	})
+
+	// Add and prune.
+	c1 := p.CloserOneWay()
+	c2 := p.CloserOneWay()
+	r.Equal(t, []uint64{c1.ID(), c2.ID()}, added)
+	r.NoError(t, c1.Close())
+	r.Equal(t, []uint64{c1.ID()}, removed)
+
+	// Detach.
+	r.NoError(t, p.CloseChildren())
+	r.Equal(t, []uint64{c1.ID(), c2.ID()}, removed)
+
+	// Adopt.
+	o := closer.New()
+	c3 := o.CloserOneWay()
+	r.NoError(t, p.Adopt(o))
+	r.Equal(t, []uint64{c1.ID(), c2.ID(), c3.ID()}, added)
+
+	// Children closed by the parent are not reported.
+	r.NoError(t, p.Close())
+	r.Len(t, removed, 2)
+}
+
+func TestCloser_SuspendTwoWay(t *testing.T) {
 	t.Parallel()
 
-	// A closer chain with only two-way closers.
 	p := closer.New()
 	c1 := p.CloserTwoWay()
-	c2 := c1.CloserTwoWay()
+	c2 := p.CloserTwoWay()
+	c3 := p.CloserTwoWay()
 
-	p.OnClose(func() error {
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
+	p.SuspendTwoWay()
+	r.NoError(t, c1.Close())
+	r.NoError(t, c2.Close())
+	r.Equal(t, 1, p.NumChildren())
 
-		r.True(t, c1.IsClosing())
-		r.True(t, c1.IsClosed())
+	// Children closed during the suspension must not propagate on resume.
+	p.ResumeTwoWay()
+	time.Sleep(50 * time.Millisecond)
+	r.False(t, p.IsClosing())
 
-		r.True(t, c2.IsClosing())
-		r.True(t, c2.IsClosed())
+	r.NoError(t, c3.Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+}
+
+func TestEndlessGrowth(t *testing.T) {
+	t.Parallel()
+
+	// Test is mainly there for 100% test coverage.
+
+	c := closer.New()
+	children := make([]closer.Closer, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		children = append(children, c.CloserOneWay())
+	}
+	for i := 0; i < 1000; i++ {
+		r.NoError(t, children[i].Close())
+	}
+}
+
+func TestCloser_BlockCloser(t *testing.T) {
+	t.Parallel()
+
+	var (
+		v  atomic.Bool
+		ch = make(chan struct{})
+		c  = closer.New()
+	)
+
+	go func() {
+		_ = c.BlockCloser(func() (err error) {
+			<-ch
+			ch <- struct{}{}
+			if c.IsClosed() {
+				v.Store(true)
+			}
+			ch <- struct{}{}
+			return nil
+		})
+	}()
+
+	ch <- struct{}{}
+	go c.Close()
+	time.Sleep(time.Second)
+	<-ch
+	<-ch
+
+	r.False(t, v.Load())
+}
+
+func TestCloser_BlockCloser_DoNotRunIfClosed(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	c.Close_()
+
+	var v atomic.Bool
+	err = c.BlockCloser(func() (err error) {
+		v.Store(true)
 		return nil
 	})
-	c1.OnClose(func() error {
-		// We closed the first-level child, at least the second-level child must be closed.
-		// The parent must not be closing yet.
-		r.False(t, p.IsClosing())
-		r.False(t, p.IsClosed())
 
-		r.True(t, c1.IsClosing())
-		r.False(t, c1.IsClosed())
+	r.False(t, v.Load())
+	r.ErrorIs(t, err, closer.ErrClosed)
+}
+
+func TestCloser_RunCloserRoutine(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		started = make(chan struct{})
+		err     = errors.New("error")
+	)
+
+	c.RunCloserRoutine(func() error {
+		close(started)
+		return err
+	})
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-started:
+	}
+
+	c.Close_()
+	r.ErrorIs(t, c.CloserError(), err)
+}
+
+func TestCloser_RunCloserRoutine_Panic(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	c.RunCloserRoutine(func() error {
+		panic("routine panic")
+	})
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+
+	err := c.CloserError()
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.ErrorContains(t, err, "routine panic")
+	r.ErrorContains(t, err, "TestCloser_RunCloserRoutine_Panic")
+}
+
+func TestCloser_SafeGo(t *testing.T) {
+	t.Parallel()
+
+	// A returning routine does not close the closer.
+	c := closer.New()
+	done := make(chan struct{})
+	c.SafeGo(func() { close(done) })
+	<-done
+	r.Eventually(t, func() bool { return c.PendingWaits() == 0 }, 3*time.Second, time.Millisecond)
+	r.False(t, c.IsClosing())
+
+	// A panicking routine closes the closer gracefully.
+	var closed atomic.Bool
+	c.OnClose(func() error {
+		closed.Store(true)
+		return nil
+	})
+	c.SafeGo(func() { panic("worker panic") })
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+
+	err := c.CloserError()
+	r.True(t, closed.Load())
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.ErrorContains(t, err, "worker panic")
+	r.ErrorContains(t, err, "TestCloser_SafeGo")
+}
+
+func TestCloser_RunCloserRoutine_DoNotRunIfClosed(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	c.Close_()
+
+	var v atomic.Bool
+	c.RunCloserRoutine(func() (err error) {
+		v.Store(true)
+		return nil
+	})
+
+	time.Sleep(time.Second)
+	r.False(t, v.Load())
+}
+
+func TestCloser_Adopt(t *testing.T) {
+	t.Parallel()
+
+	orderChan := make(chan int, 3)
+
+	a := closer.New()
+	b := closer.New()
+	for i := 0; i < 3; i++ {
+		i := i
+		c := b.CloserOneWay()
+		c.OnClose(func() error {
+			orderChan <- i
+			return nil
+		})
+	}
+	tw := b.CloserTwoWay()
+
+	r.NoError(t, a.Adopt(b))
+
+	// The adoptive closer must not close its new children.
+	r.NoError(t, b.Close())
+	r.False(t, tw.IsClosing())
+
+	// The two-way child must close its new parent.
+	r.NoError(t, tw.Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-a.ClosedChan():
+	}
+
+	// The children must have been closed in their original order.
+	for i := 0; i < 3; i++ {
+		r.Equal(t, i, <-orderChan)
+	}
+}
+
+func TestCloser_AdoptErrors(t *testing.T) {
+	t.Parallel()
+
+	a := closer.New()
+	c := a.CloserOneWay()
+	cc := c.CloserOneWay()
+
+	r.ErrorIs(t, a.Adopt(a), closer.ErrCycle)
+	r.ErrorIs(t, cc.Adopt(a), closer.ErrCycle)
+	r.ErrorIs(t, cc.Adopt(c), closer.ErrCycle)
+	r.ErrorIs(t, a.Adopt(struct{ closer.Closer }{c}), closer.ErrUnknownCloser)
+
+	b := closer.New()
+	b.Close_()
+	r.ErrorIs(t, a.Adopt(b), closer.ErrClosing)
+	r.ErrorIs(t, b.Adopt(c), closer.ErrClosing)
+	r.False(t, cc.IsClosing())
+}
+
+func TestCloser_CloseProgress(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		stepsCh = make(chan struct{})
+	)
+	_ = c.CloserOneWay()
+	c.OnClosing(func() error { return nil })
+	for i := 0; i < 3; i++ {
+		c.OnClose(func() error {
+			<-stepsCh
+			return nil
+		})
+	}
+
+	done, total := c.CloseProgress()
+	r.Zero(t, done)
+	r.Equal(t, 5, total)
+
+	go c.Close_()
+
+	// The closing func and the child close without blocking.
+	// Each close func blocks until it is released.
+	for i := 2; i <= 5; i++ {
+		r.Eventually(t, func() bool {
+			done, total = c.CloseProgress()
+			return done == i && total == 5
+		}, 3*time.Second, time.Millisecond)
+		if i < 5 {
+			stepsCh <- struct{}{}
+		}
+	}
+	<-c.ClosedChan()
+}
+
+func TestCloser_CloseParentOnly(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errTest = errors.New("test")
+		p       = closer.New()
+		c       = p.CloserTwoWay()
+		cc      = c.CloserTwoWay()
+	)
+	c.OnClosing(func() error {
+		// The parent is closed before its child.
+		r.False(t, cc.IsClosing())
+		return nil
+	})
+
+	// The two-way parent propagates the close to the root.
+	cc.CloseParentOnly(errTest)
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-p.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), errTest)
+	r.True(t, cc.IsClosed())
+	r.NoError(t, cc.CloserError())
+
+	// Root closers have no parent to close.
+	p = closer.New()
+	p.CloseParentOnly(errTest)
+	r.False(t, p.IsClosing())
+}
+
+func TestCloser_ChildByName(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c      = closer.New()
+		_      = c.CloserOneWay()
+		db     = c.CloserOneWay(closer.WithName("db"))
+		_      = c.CloserTwoWay()
+		db2    = c.CloserOneWay(closer.WithName("db"))
+		nested = db2.CloserOneWay(closer.WithName("nested"))
+	)
+
+	// The first match is returned.
+	child, ok := c.ChildByName("db")
+	r.True(t, ok)
+	r.Equal(t, db.ID(), child.ID())
+
+	// Only direct children are searched.
+	_, ok = c.ChildByName("nested")
+	r.False(t, ok)
+	child, ok = db2.ChildByName("nested")
+	r.True(t, ok)
+	r.Equal(t, nested.ID(), child.ID())
+
+	// Anonymous children have an empty name.
+	child, ok = c.ChildByName("")
+	r.True(t, ok)
+	r.Empty(t, child.Name())
+
+	// Closed one-way children are removed.
+	r.NoError(t, db.Close())
+	child, ok = c.ChildByName("db")
+	r.True(t, ok)
+	r.Equal(t, db2.ID(), child.ID())
+	r.NoError(t, db2.Close())
+	child, ok = c.ChildByName("db")
+	r.False(t, ok)
+	r.Nil(t, child)
+}
+
+func TestCloser_IsDescendantOf(t *testing.T) {
+	t.Parallel()
+
+	var (
+		root      = closer.New()
+		child     = root.CloserOneWay()
+		grandson  = child.CloserTwoWay()
+		unrelated = closer.New()
+	)
+	r.True(t, child.IsDescendantOf(root))
+	r.True(t, grandson.IsDescendantOf(root))
+	r.True(t, grandson.IsDescendantOf(child))
+	r.False(t, root.IsDescendantOf(child))
+	r.False(t, child.IsDescendantOf(unrelated))
+	r.False(t, unrelated.IsDescendantOf(root))
+	r.False(t, root.IsDescendantOf(root))
+	r.False(t, child.IsDescendantOf(nil))
+
+	// The check is safe, while the tree is modified concurrently.
+	var (
+		a, b = closer.New(), closer.New()
+		mid  = a.CloserOneWay()
+		leaf = mid.CloserOneWay()
+		done = make(chan struct{})
+	)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := b.Adopt(a); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := a.Adopt(b); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = leaf.IsDescendantOf(a)
+		r.True(t, leaf.IsDescendantOf(mid))
+	}
+	<-done
+	r.True(t, leaf.IsDescendantOf(a))
+}
+
+func TestCloser_WaitChild(t *testing.T) {
+	t.Parallel()
 
-		r.True(t, c2.IsClosing())
-		r.True(t, c2.IsClosed())
-		return nil
+	var (
+		errFlush = errors.New("flush")
+		p        = closer.New()
+		flusher  = p.CloserOneWay(closer.WithName("flusher"))
+		release  = make(chan struct{})
+	)
+	_ = p.CloserOneWay(closer.WithName("other"))
+	flusher.OnClose(func() error {
+		<-release
+		return errFlush
 	})
-	c2.OnClose(func() error {
-		// We closed the second-level child, all others must not yet be closing.
-		r.False(t, p.IsClosing())
-		r.False(t, p.IsClosed())
 
-		r.False(t, c1.IsClosing())
-		r.False(t, c1.IsClosed())
+	r.ErrorIs(t, p.WaitChild(context.Background(), "unknown"), closer.ErrChildNotFound)
 
-		r.True(t, c2.IsClosing())
-		r.False(t, c2.IsClosed())
-		return nil
-	})
+	// The wait can be canceled.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(t, p.WaitChild(ctx, "flusher"), context.DeadlineExceeded)
 
-	// Close the lowest child now.
-	err := c2.Close()
-	r.NoError(t, err)
+	waitChan := make(chan error, 1)
+	go func() {
+		waitChan <- p.WaitChild(context.Background(), "flusher")
+	}()
+	go flusher.Close_()
+
+	select {
+	case <-waitChan:
+		t.Fatal("returned before the child closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
 
-	// Wait for the parent to close. This happens in a new goroutine.
 	select {
 	case <-time.After(3 * time.Second):
 		t.Fatal("timed out")
-	case <-p.ClosedChan():
+	case err := <-waitChan:
+		r.ErrorIs(t, err, errFlush)
 	}
+}
 
-	// Repeat the test, but this time close the parent.
-	p = closer.New()
-	c1 = p.CloserTwoWay()
-	c2 = c1.CloserTwoWay()
-
-	p.OnClose(func() error {
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
-
-		r.True(t, c1.IsClosing())
-		r.True(t, c1.IsClosed())
+func TestCloser_AwaitChildrenClosed(t *testing.T) {
+	t.Parallel()
 
-		r.True(t, c2.IsClosing())
-		r.True(t, c2.IsClosed())
-		return nil
-	})
-	c1.OnClose(func() error {
-		// We close the child, so the parent and the other children must close first.
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
+	c := closer.New()
+	r.NoError(t, c.AwaitChildrenClosed(context.Background()))
 
-		r.True(t, c1.IsClosing())
-		r.False(t, c1.IsClosed())
+	a := c.CloserOneWay()
+	b := c.CloserOneWay()
+	b.CloserAddWait(1)
 
-		r.True(t, c2.IsClosing())
-		r.True(t, c2.IsClosed())
-		return nil
-	})
-	c2.OnClose(func() error {
-		// We close the child, so the parent and the other children must close first.
-		r.True(t, p.IsClosing())
-		r.False(t, p.IsClosed())
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(t, c.AwaitChildrenClosed(ctx), context.DeadlineExceeded)
 
-		r.True(t, c1.IsClosing())
-		r.False(t, c1.IsClosed())
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- c.AwaitChildrenClosed(context.Background())
+	}()
 
-		r.True(t, c2.IsClosing())
-		r.False(t, c2.IsClosed())
-		return nil
-	})
+	// Children added during the wait are not awaited.
+	time.Sleep(20 * time.Millisecond)
+	_ = c.CloserOneWay()
 
-	// Close the parent this time.
-	err = p.Close()
-	r.NoError(t, err)
-	r.True(t, p.IsClosed())
+	r.NoError(t, a.Close())
+	go b.Close_()
+	b.CloserDone()
+	r.NoError(t, <-errChan)
+	r.False(t, c.IsClosing())
 }
 
-func testTwoWayRoutines(t *testing.T) {
+func TestCloser_State(t *testing.T) {
 	t.Parallel()
 
-	// One parent with 2 direct, two-way children.
-	p := closer.New()
-	c1 := p.CloserTwoWay()
-	c2 := p.CloserTwoWay()
+	c := closer.New()
+	r.Equal(t, closer.StateOpen, c.State())
+	r.Zero(t, c.CloseDuration())
 
-	p.OnClose(func() error {
-		// Both children must be closed
-		r.True(t, c1.IsClosed())
-		r.True(t, c2.IsClosed())
+	c.OnClose(func() error {
+		r.Equal(t, closer.StateClosing, c.State())
+		time.Sleep(10 * time.Millisecond)
+		r.Greater(t, c.CloseDuration(), time.Duration(0))
 		return nil
 	})
+	r.NoError(t, c.Close())
+	r.Equal(t, closer.StateClosed, c.State())
 
-	// This is a child of the first child. This closer
-	// will be closed.
-	cc1 := c1.CloserTwoWay()
-	cc1.OnClose(func() error {
-		r.False(t, p.IsClosed())
-		r.False(t, c1.IsClosed())
-		r.False(t, c2.IsClosed())
-		return nil
-	})
+	d := c.CloseDuration()
+	r.GreaterOrEqual(t, d, 10*time.Millisecond)
+	r.Equal(t, d, c.CloseDuration())
 
-	c1.OnClose(func() error {
-		r.True(t, cc1.IsClosed())
-		r.False(t, p.IsClosed())
-		r.False(t, c2.IsClosed())
-		return nil
-	})
+	r.Equal(t, "open", closer.StateOpen.String())
+	r.Equal(t, "closing", closer.StateClosing.String())
+	r.Equal(t, "closed", closer.StateClosed.String())
+}
 
-	p.CloserAddWait(1)
-	c1.CloserAddWait(1)
-	c2.CloserAddWait(1)
+func TestCloser_ForEachChild(t *testing.T) {
+	t.Parallel()
 
-	retChan := make(chan error, 4)
-	f := func(c closer.Closer) {
-		var err error
-		select {
-		case <-c.ClosingChan():
-			err = c.CloseAndDone()
-		case <-time.After(time.Second):
-			err = errors.New("routine timed out")
-		}
-		retChan <- err
+	c := closer.New()
+	children := []closer.Closer{
+		c.CloserOneWay(closer.WithName("a")),
+		c.CloserTwoWay(closer.WithName("b")),
+		c.CloserOneWay(closer.WithName("c")),
 	}
-	go f(c1)
-	go f(c2)
-	go f(p)
+	// Grandchildren are not iterated.
+	_ = children[0].CloserOneWay()
 
-	// Close a child. This should close c1, c2 and p.
-	err := c1.Close()
-	r.NoError(t, err)
-	for i := 0; i < 3; i++ {
-		select {
-		case <-time.After(time.Second):
-			t.Fatal("main timed out")
-		case err = <-retChan:
-			r.NoError(t, err)
-		}
+	var names []string
+	c.ForEachChild(func(child closer.Closer) bool {
+		names = append(names, child.Name())
+		return true
+	})
+	r.Equal(t, []string{"a", "b", "c"}, names)
+
+	// Stop early and close a child from within fn.
+	names = nil
+	c.ForEachChild(func(child closer.Closer) bool {
+		names = append(names, child.Name())
+		r.NoError(t, child.Close())
+		return false
+	})
+	r.Equal(t, []string{"a"}, names)
+	r.Equal(t, 2, c.NumChildren())
+	r.NoError(t, c.Close())
+}
+
+func TestCloser_Children(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	r.Empty(t, c.Children())
+
+	a := c.CloserOneWay()
+	b := c.CloserOneWay()
+	_ = a.CloserOneWay()
+	snapshot := c.Children()
+	r.Equal(t, []closer.Closer{a, b}, snapshot)
+
+	// The snapshot is independent of later changes and vice versa.
+	d := c.CloserOneWay()
+	r.NoError(t, a.Close())
+	r.Equal(t, []closer.Closer{a, b}, snapshot)
+	snapshot[0] = nil
+	r.ElementsMatch(t, []closer.Closer{b, d}, c.Children())
+
+	// The children can be closed individually.
+	for _, child := range c.Children() {
+		r.NoError(t, child.CloseCtx(context.Background()))
 	}
+	r.Zero(t, c.NumChildren())
+	r.False(t, c.IsClosing())
 }
 
-func testTwoWayParentWaitGroup(t *testing.T) {
+func TestCloser_WouldBlock(t *testing.T) {
 	t.Parallel()
 
-	p := closer.New()
-	c := p.CloserTwoWay()
+	c := closer.New()
+	r.False(t, c.WouldBlock())
 
-	go func() {
-		p.CloserAddWait(1)
-		defer p.CloseAndDone_()
+	// Pending waits.
+	c.CloserAddWait(1)
+	r.True(t, c.WouldBlock())
+	c.CloserDone()
+	r.False(t, c.WouldBlock())
 
-		c.Close_()
-	}()
+	// Open children.
+	child := c.CloserOneWay()
+	r.True(t, c.WouldBlock())
+	r.NoError(t, child.Close())
+	r.False(t, c.WouldBlock())
 
-	select {
-	case <-time.After(3 * time.Second):
-		t.Fatal("timed out")
-	case <-p.ClosedChan():
-	}
+	r.NoError(t, c.Close())
+	r.False(t, c.WouldBlock())
 }
 
-func TestEndlessGrowth(t *testing.T) {
+func TestCloser_CountOpen(t *testing.T) {
 	t.Parallel()
 
-	// Test is mainly there for 100% test coverage.
-
-	c := closer.New()
-	children := make([]closer.Closer, 0, 1000)
-	for i := 0; i < 1000; i++ {
-		children = append(children, c.CloserOneWay())
-	}
-	for i := 0; i < 1000; i++ {
-		r.NoError(t, children[i].Close())
+	var (
+		c     = closer.New()
+		conns = make([]closer.Closer, 3)
+	)
+	for i := range conns {
+		conns[i] = c.CloserOneWay()
 	}
+	conns[0].CloserOneWay()
+	r.Equal(t, 5, c.CountOpen())
+
+	// A closing child is counted until it is closed.
+	release := make(chan struct{})
+	conns[1].OnClose(func() error {
+		<-release
+		return nil
+	})
+	go conns[1].Close_()
+	<-conns[1].ClosingChan()
+	r.Equal(t, 5, c.CountOpen())
+
+	close(release)
+	<-conns[1].ClosedChan()
+	r.NoError(t, conns[0].Close())
+	r.Equal(t, 2, c.CountOpen())
+	r.Equal(t, 1, conns[2].CountOpen())
+
+	r.NoError(t, c.Close())
+	r.Zero(t, c.CountOpen())
 }
 
-func TestCloser_BlockCloser(t *testing.T) {
+func TestCloser_ManagedGoroutines(t *testing.T) {
 	t.Parallel()
 
 	var (
-		v  atomic.Bool
-		ch = make(chan struct{})
-		c  = closer.New()
+		c     = closer.New()
+		child = c.CloserOneWay()
 	)
+	r.Zero(t, c.ManagedGoroutines())
 
-	go func() {
-		_ = c.BlockCloser(func() (err error) {
-			<-ch
-			ch <- struct{}{}
-			if c.IsClosed() {
-				v.Store(true)
-			}
-			ch <- struct{}{}
+	// The routines block until the closer is closing.
+	for i := 0; i < 3; i++ {
+		c.RunCloserRoutine(func() error {
+			<-c.ClosingChan()
 			return nil
 		})
-	}()
+	}
+	child.RunEvery(time.Hour, func() error { return nil })
+	child.CloserAddWait(1)
+	r.Equal(t, 5, c.ManagedGoroutines())
+	r.Equal(t, 2, child.ManagedGoroutines())
+
+	// The count decreases as the routines finish.
+	child.CloserDone()
+	r.Equal(t, 4, c.ManagedGoroutines())
+	r.Equal(t, 1, child.ManagedGoroutines())
+
+	r.NoError(t, c.Close())
+	r.Zero(t, c.ManagedGoroutines())
+	r.Zero(t, child.ManagedGoroutines())
+}
 
-	ch <- struct{}{}
-	go c.Close()
-	time.Sleep(time.Second)
-	<-ch
-	<-ch
+func TestCloser_ID(t *testing.T) {
+	t.Parallel()
 
-	r.False(t, v.Load())
+	const n = 1000
+
+	var (
+		ids   = make(map[uint64]closer.Closer, 2*n)
+		idsMx sync.Mutex
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c := closer.New()
+			cc := c.CloserOneWay()
+
+			idsMx.Lock()
+			ids[c.ID()] = c
+			ids[cc.ID()] = cc
+			idsMx.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	r.Len(t, ids, 2*n)
+	for id, c := range ids {
+		r.NotZero(t, id)
+		r.Equal(t, id, c.ID())
+	}
 }
 
-func TestCloser_BlockCloser_DoNotRunIfClosed(t *testing.T) {
+func TestCloser_RunEvery(t *testing.T) {
 	t.Parallel()
 
-	c := closer.New()
-	c.Close_()
-
-	var v atomic.Bool
-	err = c.BlockCloser(func() (err error) {
-		v.Store(true)
+	var (
+		calls atomic.Int64
+		c     = closer.New()
+	)
+	c.RunEvery(time.Millisecond, func() error {
+		calls.Add(1)
 		return nil
 	})
+	r.Eventually(t, func() bool {
+		return calls.Load() >= 3
+	}, 3*time.Second, time.Millisecond)
+
+	// The closer waits for the routine, which stops calling f.
+	r.NoError(t, c.Close())
+	n := calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	r.Equal(t, n, calls.Load())
+
+	// An error closes the closer.
+	errTick := errors.New("tick")
+	c = closer.New()
+	c.RunEvery(time.Millisecond, func() error {
+		return errTick
+	})
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), errTick)
 
-	r.False(t, v.Load())
-	r.ErrorIs(t, err, closer.ErrClosed)
+	// Nothing runs for a closed closer.
+	c.RunEvery(time.Millisecond, func() error {
+		calls.Add(1)
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+	r.Equal(t, n, calls.Load())
 }
 
-func TestCloser_RunCloserRoutine(t *testing.T) {
+func TestCloser_OpenLeaves(t *testing.T) {
 	t.Parallel()
 
 	var (
-		c       = closer.New()
-		started = make(chan struct{})
-		err     = errors.New("error")
+		p   = closer.New()
+		c1  = p.CloserOneWay()
+		c2  = p.CloserOneWay()
+		cc1 = c1.CloserOneWay()
+		cc2 = c1.CloserOneWay()
 	)
+	r.ElementsMatch(t, []closer.Closer{c2, cc1, cc2}, p.OpenLeaves())
+
+	r.NoError(t, cc1.Close())
+	r.NoError(t, c2.Close())
+	r.ElementsMatch(t, []closer.Closer{cc2}, p.OpenLeaves())
+
+	// A closer stuck in its closing state stays a leaf.
+	cc2.CloserAddWait(1)
+	go p.Close_()
+	r.Eventually(t, func() bool {
+		return cc2.IsClosing()
+	}, 3*time.Second, time.Millisecond)
+	r.ElementsMatch(t, []closer.Closer{cc2}, p.OpenLeaves())
+
+	cc2.CloserDone()
+	<-p.ClosedChan()
+	r.Empty(t, p.OpenLeaves())
+}
 
-	c.RunCloserRoutine(func() error {
-		close(started)
-		return err
+func TestCloser_CloseAfter(t *testing.T) {
+	t.Parallel()
+
+	var (
+		root     = closer.New()
+		a        = root.CloserOneWay()
+		b        = root.CloserOneWay()
+		release  = make(chan struct{})
+		aClosed  atomic.Bool
+		bRunning atomic.Bool
+	)
+	a.OnClose(func() error {
+		<-release
+		aClosed.Store(true)
+		return nil
+	})
+	b.OnClose(func() error {
+		bRunning.Store(true)
+		r.True(t, aClosed.Load())
+		return nil
 	})
+	r.NoError(t, b.CloseAfter(a, 3*time.Second))
 
+	go b.Close_()
+	time.Sleep(50 * time.Millisecond)
+	r.False(t, bRunning.Load())
+
+	go a.Close_()
+	close(release)
 	select {
 	case <-time.After(3 * time.Second):
 		t.Fatal("timed out")
-	case <-started:
+	case <-b.ClosedChan():
 	}
-
-	c.Close_()
-	r.ErrorIs(t, c.CloserError(), err)
+	r.True(t, bRunning.Load())
+	r.NoError(t, b.CloserError())
+
+	// The dependency times out.
+	c, d := closer.New(), closer.New()
+	r.NoError(t, c.CloseAfter(d, 50*time.Millisecond))
+	r.ErrorIs(t, c.Close(), closer.ErrDependencyTimeout)
+	r.ErrorIs(t, c.CloseAfter(d, time.Second), closer.ErrClosing)
 }
 
-func TestCloser_RunCloserRoutine_DoNotRunIfClosed(t *testing.T) {
+func TestCloser_CloseAfterCommonAncestor(t *testing.T) {
 	t.Parallel()
 
-	c := closer.New()
-	c.Close_()
+	// The dependent closer is created first, so the root closes it first.
+	var (
+		root    = closer.New()
+		b       = root.CloserOneWay()
+		branch  = root.CloserOneWay()
+		a       = branch.CloserOneWay()
+		order   []string
+		orderMx sync.Mutex
+	)
+	add := func(s string) closer.CloseFunc {
+		return func() error {
+			orderMx.Lock()
+			order = append(order, s)
+			orderMx.Unlock()
+			return nil
+		}
+	}
+	branch.OnClosing(add("branch closing"))
+	a.OnClose(add("a"))
+	b.OnClose(add("b"))
+	r.NoError(t, b.CloseAfter(a, 3*time.Second))
+
+	start := time.Now()
+	r.NoError(t, root.Close())
+	r.Less(t, time.Since(start), time.Second)
+	r.Equal(t, []string{"branch closing", "a", "b"}, order)
+}
 
-	var v atomic.Bool
-	c.RunCloserRoutine(func() (err error) {
-		v.Store(true)
-		return nil
-	})
+func TestCloser_CloseAfterCycle(t *testing.T) {
+	t.Parallel()
 
-	time.Sleep(time.Second)
-	r.False(t, v.Load())
+	var (
+		a = closer.New()
+		b = closer.New()
+		c = closer.New()
+		p = closer.New()
+		d = p.CloserOneWay()
+	)
+	r.ErrorIs(t, a.CloseAfter(a, time.Second), closer.ErrCycle)
+	r.NoError(t, a.CloseAfter(b, time.Second))
+	r.NoError(t, b.CloseAfter(c, time.Second))
+	r.ErrorIs(t, c.CloseAfter(a, time.Second), closer.ErrCycle)
+	r.ErrorIs(t, b.CloseAfter(a, time.Second), closer.ErrCycle)
+
+	// A closer can not wait for its ancestors, which wait for the closer itself.
+	r.ErrorIs(t, d.CloseAfter(p, time.Second), closer.ErrCycle)
+	r.NoError(t, p.CloseAfter(d, time.Second))
 }
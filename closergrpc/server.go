@@ -0,0 +1,74 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package closergrpc integrates gRPC servers with the closer package.
+package closergrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	"google.golang.org/grpc"
+)
+
+// ErrForcedStop indicates that the graceful stop of a server did not finish
+// in time and the server has been stopped forcefully.
+var ErrForcedStop = errors.New("grpc server stopped forcefully")
+
+// WrapServer registers the graceful shutdown of the server as close func of the closer.
+// When the closer closes, GracefulStop() waits for the pending RPCs to finish.
+// If the graceful stop exceeds the budget, the server is stopped forcefully with
+// Stop() and ErrForcedStop is joined with the closer's other errors.
+// The budget is bounded by the close context, see closer.Closer.CloseCtx() and
+// closer.Closer.SetCloseTimeout(), and by the timeout, if it is greater than zero.
+func WrapServer(c closer.Closer, srv *grpc.Server, timeout time.Duration) {
+	c.OnCloseCtx(func(ctx context.Context) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			// Stop closes all connections and the pending graceful stop returns.
+			srv.Stop()
+			<-stopped
+			return errors.Join(ErrForcedStop, ctx.Err())
+		}
+	})
+}
@@ -0,0 +1,162 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closergrpc_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	"github.com/desertbit/closer/v3/closergrpc"
+	r "github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// slowService is a gRPC service with a single unary method,
+// which blocks until the release chan is closed.
+type slowService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func newServer(t testing.TB) (*grpc.Server, *slowService, *grpc.ClientConn) {
+	s := &slowService{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Slow",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Wait",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				if err := dec(new(emptypb.Empty)); err != nil {
+					return nil, err
+				}
+				s.started <- struct{}{}
+				select {
+				case <-s.release:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				return new(emptypb.Empty), nil
+			},
+		}},
+	}, struct{}{})
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return srv, s, conn
+}
+
+// call invokes the blocking method and returns its error on the channel.
+func call(conn *grpc.ClientConn) <-chan error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- conn.Invoke(context.Background(), "/test.Slow/Wait", new(emptypb.Empty), new(emptypb.Empty))
+	}()
+	return errs
+}
+
+func ExampleWrapServer() {
+	c := closer.New()
+	srv := grpc.NewServer()
+	closergrpc.WrapServer(c, srv, 10*time.Second)
+
+	// Register the services and serve...
+
+	fmt.Println(c.Close())
+	// Output: <nil>
+}
+
+func TestWrapServer_Graceful(t *testing.T) {
+	t.Parallel()
+
+	srv, s, conn := newServer(t)
+	c := closer.New()
+	closergrpc.WrapServer(c, srv, 0)
+
+	// The graceful stop waits for the in-flight call.
+	errs := call(conn)
+	<-s.started
+	closeErr := make(chan error, 1)
+	go func() { closeErr <- c.Close() }()
+
+	select {
+	case <-c.ClosedChan():
+		t.Fatal("closed before the in-flight call finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(s.release)
+	r.NoError(t, <-errs)
+	r.NoError(t, <-closeErr)
+}
+
+func TestWrapServer_Forced(t *testing.T) {
+	t.Parallel()
+
+	// The close timeout of the closer bounds the graceful stop.
+	srv, s, conn := newServer(t)
+	c := closer.New(closer.WithCloseTimeout(50 * time.Millisecond))
+	closergrpc.WrapServer(c, srv, 0)
+
+	errs := call(conn)
+	<-s.started
+	r.ErrorIs(t, c.Close(), closergrpc.ErrForcedStop)
+	r.Error(t, <-errs)
+
+	// The timeout bounds the graceful stop as well.
+	srv, s, conn = newServer(t)
+	c = closer.New()
+	closergrpc.WrapServer(c, srv, 50*time.Millisecond)
+
+	errs = call(conn)
+	<-s.started
+	err := c.Close()
+	r.ErrorIs(t, err, closergrpc.ErrForcedStop)
+	r.ErrorIs(t, err, context.DeadlineExceeded)
+	r.Error(t, <-errs)
+}
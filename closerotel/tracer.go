@@ -0,0 +1,87 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package closerotel traces the closing order of closers with OpenTelemetry.
+// It is a separate module to keep the closer package free of dependencies.
+package closerotel
+
+import (
+	"context"
+
+	"github.com/desertbit/closer/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer returns a closer option, which creates a span for the close of
+// the closer and each of its children. The funcs of the closing order are
+// traced as child spans of their closer's span and errors are recorded as
+// span events. The close reason is recorded as the closer.reason attribute.
+// The option is inherited by the children, so the span hierarchy matches the
+// closer tree.
+func WithTracer(t trace.Tracer) closer.Option {
+	return closer.WithCloseTracer(tracer{t: t})
+}
+
+// A tracer implements the closer.CloseTracer interface for an OpenTelemetry tracer.
+type tracer struct {
+	t trace.Tracer
+}
+
+// Implements the closer.CloseTracer interface.
+func (tr tracer) StartClose(ctx context.Context, c closer.Closer) (context.Context, func(err error)) {
+	ctx, span := tr.t.Start(ctx, "closer.Close", trace.WithAttributes(
+		attribute.String("closer.name", c.Name()),
+		attribute.Int64("closer.id", int64(c.ID())),
+	))
+	return ctx, func(err error) {
+		if reason := c.CloseReasonText(); reason != "" {
+			span.SetAttributes(attribute.String("closer.reason", reason))
+		}
+		end(span, err)
+	}
+}
+
+// Implements the closer.CloseTracer interface.
+func (tr tracer) StartStep(ctx context.Context, step closer.PlanStep) func(err error) {
+	_, span := tr.t.Start(ctx, "closer."+string(step.Phase), trace.WithAttributes(
+		attribute.String("closer.func", step.Name),
+	))
+	return func(err error) {
+		end(span, err)
+	}
+}
+
+// end records the error, if any, and ends the span.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,107 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closerotel_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	"github.com/desertbit/closer/v3/closerotel"
+	r "github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracer(t *testing.T) {
+	t.Parallel()
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	errChild := errors.New("child")
+	c := closer.New(closer.WithName("root"), closerotel.WithTracer(tp.Tracer("test")))
+	c.OnClosing(func() error { return nil })
+	child := c.CloserOneWay(closer.WithName("child"))
+	child.OnClose(func() error { return errChild })
+	r.ErrorIs(t, c.CloseWithReason("shutdown"), errChild)
+
+	// Map the spans by their span ID to check the hierarchy.
+	spans := sr.Ended()
+	r.Len(t, spans, 4)
+	byID := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range spans {
+		byID[s.SpanContext().SpanID().String()] = s
+	}
+	attr := func(s sdktrace.ReadOnlySpan, key string) string {
+		for _, a := range s.Attributes() {
+			if a.Key == attribute.Key(key) {
+				return a.Value.AsString()
+			}
+		}
+		return ""
+	}
+	closerName := func(s sdktrace.ReadOnlySpan) string {
+		return attr(s, "closer.name")
+	}
+	parent := func(s sdktrace.ReadOnlySpan) sdktrace.ReadOnlySpan {
+		return byID[s.Parent().SpanID().String()]
+	}
+
+	var root, childSpan, closing, childClose sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		switch {
+		case s.Name() == "closer.Close" && closerName(s) == "root":
+			root = s
+		case s.Name() == "closer.Close" && closerName(s) == "child":
+			childSpan = s
+		case s.Name() == "closer.closing":
+			closing = s
+		case s.Name() == "closer.close":
+			childClose = s
+		}
+	}
+	r.NotNil(t, root)
+	r.NotNil(t, childSpan)
+	r.NotNil(t, closing)
+	r.NotNil(t, childClose)
+
+	r.False(t, root.Parent().IsValid())
+	r.Equal(t, root, parent(childSpan))
+	r.Equal(t, root, parent(closing))
+	r.Equal(t, childSpan, parent(childClose))
+
+	// The errors are recorded as span events.
+	r.Equal(t, codes.Error, childClose.Status().Code)
+	r.Len(t, childClose.Events(), 1)
+	r.Equal(t, codes.Error, root.Status().Code)
+	r.Equal(t, codes.Unset, closing.Status().Code)
+	r.Equal(t, "shutdown", attr(root, "closer.reason"))
+}
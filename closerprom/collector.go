@@ -0,0 +1,113 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package closerprom exposes the state of a closer as Prometheus metrics.
+// It is a separate module to keep the closer package free of dependencies.
+package closerprom
+
+import (
+	"github.com/desertbit/closer/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	stateDesc = prometheus.NewDesc(
+		"closer_state",
+		"The lifecycle state of the closer: 0 open, 1 closing, 2 closed.",
+		[]string{"name"}, nil,
+	)
+	childrenDesc = prometheus.NewDesc(
+		"closer_children",
+		"The number of direct children of the closer.",
+		[]string{"name"}, nil,
+	)
+	pendingWaitsDesc = prometheus.NewDesc(
+		"closer_pending_waits",
+		"The current counter of the closer's wait group.",
+		[]string{"name"}, nil,
+	)
+	closeDurationDesc = prometheus.NewDesc(
+		"closer_close_duration_seconds",
+		"The duration of the close, which is still increasing while the closer is closing.",
+		[]string{"name"}, nil,
+	)
+	closeErrorsDesc = prometheus.NewDesc(
+		"closer_close_errors_total",
+		"The number of errors the closer has been closed with.",
+		[]string{"name"}, nil,
+	)
+)
+
+// A collector implements the prometheus.Collector interface for a closer.
+type collector struct {
+	c closer.Closer
+}
+
+// NewCollector returns a prometheus.Collector, which exposes the state,
+// the number of children and pending waits, the close duration and the
+// number of close errors of the closer. The metrics are labeled with the
+// name of the closer. Register a collector for each closer of interest,
+// each with a unique name.
+func NewCollector(c closer.Closer) prometheus.Collector {
+	return &collector{c: c}
+}
+
+// Describe implements the prometheus.Collector interface.
+func (col *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stateDesc
+	ch <- childrenDesc
+	ch <- pendingWaitsDesc
+	ch <- closeDurationDesc
+	ch <- closeErrorsDesc
+}
+
+// Collect implements the prometheus.Collector interface.
+func (col *collector) Collect(ch chan<- prometheus.Metric) {
+	// Use a consistent snapshot of the closer.
+	s := col.c.Stats()
+
+	ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, float64(s.State), s.Name)
+	ch <- prometheus.MustNewConstMetric(childrenDesc, prometheus.GaugeValue, float64(s.NumChildren), s.Name)
+	ch <- prometheus.MustNewConstMetric(pendingWaitsDesc, prometheus.GaugeValue, float64(s.PendingWaits), s.Name)
+	ch <- prometheus.MustNewConstMetric(closeDurationDesc, prometheus.GaugeValue, s.CloseDuration.Seconds(), s.Name)
+	ch <- prometheus.MustNewConstMetric(closeErrorsDesc, prometheus.CounterValue, float64(countErrors(col.c.CloserError())), s.Name)
+}
+
+// countErrors returns the number of errors joined in err.
+func countErrors(err error) int {
+	if err == nil {
+		return 0
+	}
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		n := 0
+		for _, e := range u.Unwrap() {
+			n += countErrors(e)
+		}
+		return n
+	}
+	return 1
+}
@@ -0,0 +1,94 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closerprom_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	"github.com/desertbit/closer/v3/closerprom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New(closer.WithName("app"))
+	_ = c.CloserOneWay()
+	_ = c.CloserOneWay()
+	c.CloserAddWait(1)
+
+	reg := prometheus.NewPedanticRegistry()
+	r.NoError(t, reg.Register(closerprom.NewCollector(c)))
+
+	r.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP closer_children The number of direct children of the closer.
+# TYPE closer_children gauge
+closer_children{name="app"} 2
+# HELP closer_pending_waits The current counter of the closer's wait group.
+# TYPE closer_pending_waits gauge
+closer_pending_waits{name="app"} 1
+# HELP closer_state The lifecycle state of the closer: 0 open, 1 closing, 2 closed.
+# TYPE closer_state gauge
+closer_state{name="app"} 0
+# HELP closer_close_errors_total The number of errors the closer has been closed with.
+# TYPE closer_close_errors_total counter
+closer_close_errors_total{name="app"} 0
+`), "closer_children", "closer_pending_waits", "closer_state", "closer_close_errors_total"))
+
+	c.OnClose(
+		func() error { return errors.New("a") },
+		func() error { return errors.New("b") },
+	)
+	c.CloserDone()
+	r.Error(t, c.Close())
+
+	r.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP closer_children The number of direct children of the closer.
+# TYPE closer_children gauge
+closer_children{name="app"} 0
+# HELP closer_state The lifecycle state of the closer: 0 open, 1 closing, 2 closed.
+# TYPE closer_state gauge
+closer_state{name="app"} 2
+# HELP closer_close_errors_total The number of errors the closer has been closed with.
+# TYPE closer_close_errors_total counter
+closer_close_errors_total{name="app"} 2
+`), "closer_children", "closer_state", "closer_close_errors_total"))
+}
+
+func TestCollector_Lint(t *testing.T) {
+	t.Parallel()
+
+	problems, err := testutil.CollectAndLint(closerprom.NewCollector(closer.New()))
+	r.NoError(t, err)
+	r.Empty(t, problems)
+}
@@ -0,0 +1,2319 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+// Package closertest provides helpers to test code that uses the closer package.
+package closertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+)
+
+// Ensure the Mock implements the Closer interface.
+var _ closer.Closer = (*Mock)(nil)
+
+// Used to assign unique IDs to mocks.
+var lastID atomic.Uint64
+
+// A Mock is a closer.Closer for unit tests of code that accepts a closer.
+// It records all calls and the registered funcs, and the test controls
+// when it is closing or closed with SimulateClosing() and SimulateClosed().
+//
+// In contrast to a real closer, a Mock does not spawn goroutines to run
+// routines or to close parents. Close() executes all registered funcs
+// synchronously in the same order as a real closer. Routines added with
+// RunCloserRoutine() or RunEvery() are only recorded, see Routines().
+// Children of a Mock are Mocks as well. They are closed by their parent,
+// but never close their parent.
+type Mock struct {
+	id uint64
+
+	mx     sync.Mutex
+	name   string
+	labels map[string]string
+	calls  map[string]int
+
+	closingChan     chan struct{}
+	closedChan      chan struct{}
+	softClosingChan chan struct{}
+	closeStarted    bool
+	closeErr        error
+	ownErr          error
+	appendedErrs    []error
+	firstErr        error
+	closeReason     string
+	closedByParent  bool
+	closeCtx        context.Context
+	closingAt       time.Time
+	closedAt        time.Time
+
+	closingFuncs        []closer.CloseFunc
+	beforeChildrenFuncs []closer.CloseFunc
+	closeFuncs          []closer.CloseFunc
+	closeFuncDeps       map[int][]int
+	closeFuncPhases     map[int]string
+	phaseOrder          []string
+	closedFuncs         []func()
+	finalizer           func(err error)
+	resourceStats       map[string]*closer.ResourceStat
+	paused              bool
+	pausedChan          chan struct{}
+	resumedChan         chan struct{}
+	finalFuncs          []closer.FinalFunc
+	lastFuncs           []closer.CloseFunc
+	routines            []func() error
+	childAddedFuncs     []func(child closer.Closer)
+	childRemovedFuncs   []func(child closer.Closer)
+	cancels             []context.CancelCauseFunc
+	closedCancels       []context.CancelCauseFunc
+	broadcastChans      []chan<- struct{}
+	closeDeps           []closer.Closer
+	deadline            time.Time
+	closeTimeout        time.Duration
+	waits               int
+
+	parent         *Mock
+	children       []*Mock
+	closedChildren []*Mock
+	stepResults    []closer.StepResult
+}
+
+// NewMock creates a new Mock, which is open.
+func NewMock() *Mock {
+	return &Mock{
+		id:              lastID.Add(1),
+		calls:           make(map[string]int),
+		closingChan:     make(chan struct{}),
+		closedChan:      make(chan struct{}),
+		softClosingChan: make(chan struct{}),
+	}
+}
+
+//################//
+//### Controls ###//
+//################//
+
+// SetName sets the name returned by Name().
+// Implements the closer.Closer interface.
+func (m *Mock) SetName(name string) {
+	m.mx.Lock()
+	m.calls["SetName"]++
+	m.name = name
+	m.mx.Unlock()
+}
+
+// SetLabels sets the labels returned by Labels().
+func (m *Mock) SetLabels(labels map[string]string) {
+	m.mx.Lock()
+	m.labels = labels
+	m.mx.Unlock()
+}
+
+// SimulateClosing puts the mock into the closing state without
+// executing any registered funcs. The closing chan is closed and
+// all contexts returned by Context() are canceled.
+func (m *Mock) SimulateClosing() {
+	m.mx.Lock()
+	m.setClosing()
+	m.mx.Unlock()
+}
+
+// SimulateClosed puts the mock into the closed state with the given error
+// without executing any registered funcs. Both the closing and the closed
+// chan are closed.
+func (m *Mock) SimulateClosed(err error) {
+	m.mx.Lock()
+	if m.isClosed() {
+		m.mx.Unlock()
+		return
+	}
+	m.setClosing()
+	m.closeErr = errors.Join(m.closeErr, err)
+	m.ownErr = m.closeErr
+	closedFuncs := m.setClosed()
+	m.mx.Unlock()
+
+	for _, f := range closedFuncs {
+		callClosedFunc(f)
+	}
+}
+
+//#################//
+//### Recording ###//
+//#################//
+
+// Calls returns how often the method with the given name has been called,
+// e.g. Calls("CloserAddWait").
+func (m *Mock) Calls(method string) int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.calls[method]
+}
+
+// ClosingFuncs returns the funcs registered with OnClosing() and OnClosingCtx(),
+// which have not been executed yet.
+func (m *Mock) ClosingFuncs() []closer.CloseFunc {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.CloseFunc(nil), m.closingFuncs...)
+}
+
+// BeforeChildrenFuncs returns the funcs registered with OnBeforeChildrenClose(),
+// which have not been executed yet.
+func (m *Mock) BeforeChildrenFuncs() []closer.CloseFunc {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.CloseFunc(nil), m.beforeChildrenFuncs...)
+}
+
+// CloseFuncs returns the funcs registered with OnClose(), Apply(), OnCloseBenign(), OnCloseCtx(),
+// OnCloseCloser(), OnCloseNeeds(), OnCloseDep(), OnCloseOnce(), OnCloseIf(), OnCloseTimeout(), Manage() and Defer(), which have not been executed yet.
+func (m *Mock) CloseFuncs() []closer.CloseFunc {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.CloseFunc(nil), m.closeFuncs...)
+}
+
+// FinalFuncs returns the funcs registered with OnCloseFinal(),
+// which have not been executed yet.
+func (m *Mock) FinalFuncs() []closer.FinalFunc {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.FinalFunc(nil), m.finalFuncs...)
+}
+
+// LastFuncs returns the funcs registered with OnCloseLast(),
+// which have not been executed yet.
+func (m *Mock) LastFuncs() []closer.CloseFunc {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.CloseFunc(nil), m.lastFuncs...)
+}
+
+// Routines returns the funcs passed to RunCloserRoutine(), RunEvery() and SafeGo(),
+// and the workers of SpawnWorkers() and SpawnWorkersErr().
+// They are not executed by the mock.
+func (m *Mock) Routines() []func() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]func() error(nil), m.routines...)
+}
+
+// ChildMocks returns the current children of the mock.
+func (m *Mock) ChildMocks() []*Mock {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]*Mock(nil), m.children...)
+}
+
+// CloseDeps returns the closers passed to CloseAfter().
+func (m *Mock) CloseDeps() []closer.Closer {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return append([]closer.Closer(nil), m.closeDeps...)
+}
+
+// Deadline returns the deadline set with SetDeadline().
+func (m *Mock) Deadline() time.Time {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.deadline
+}
+
+// CloseTimeout returns the timeout set with SetCloseTimeout().
+func (m *Mock) CloseTimeout() time.Duration {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.closeTimeout
+}
+
+//###############//
+//### Closing ###//
+//###############//
+
+// Implements the closer.Closer interface.
+func (m *Mock) Close() error {
+	m.record("Close")
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseBottomUp() error {
+	m.record("CloseBottomUp")
+	return m.closeBottomUp(false)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosingDeadline() (time.Time, bool) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ClosingDeadline"]++
+	if !m.isClosing() {
+		return time.Time{}, false
+	}
+	var d time.Time
+	if m.closeCtx != nil {
+		d, _ = m.closeCtx.Deadline()
+	}
+	if m.deadline.After(m.closingAt) && (d.IsZero() || m.deadline.Before(d)) {
+		d = m.deadline
+	}
+	return d, !d.IsZero()
+}
+
+// Implements the closer.Closer interface.
+// The wait group is not awaited, so the timeout never expires.
+func (m *Mock) CloseWithWaitTimeout(d time.Duration) error {
+	m.record("CloseWithWaitTimeout")
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+// The close funcs are not retried.
+func (m *Mock) CloseWithRetry(attempts int, backoff time.Duration) error {
+	m.record("CloseWithRetry")
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseTree() closer.TreeResult {
+	m.record("CloseTree")
+	_ = m.close()
+	return m.treeResult()
+}
+
+// Implements the closer.Closer interface.
+// The steps are collected on every close of the mock.
+func (m *Mock) CloseAndCollect() []closer.StepResult {
+	m.record("CloseAndCollect")
+	_ = m.close()
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]closer.StepResult(nil), m.stepResults...)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseAndReturnFirst() error {
+	m.record("CloseAndReturnFirst")
+	_ = m.close()
+	return m.getFirstErr()
+}
+
+// Implements the closer.Closer interface.
+// The waits are not bounded, but the context is passed to the funcs of OnCloseCtx().
+func (m *Mock) CloseCtx(ctx context.Context) error {
+	m.mx.Lock()
+	m.calls["CloseCtx"]++
+	if !m.isClosing() {
+		m.closeCtx = ctx
+	}
+	m.mx.Unlock()
+
+	return errors.Join(m.close(), ctx.Err())
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Close_() {
+	m.record("Close_")
+	_ = m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseWithErr(err error) {
+	m.record("CloseWithErr")
+	m.addError(err)
+	_ = m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) AppendCloseErr(err error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["AppendCloseErr"]++
+	if err != nil && !m.isClosed() {
+		m.appendedErrs = append(m.appendedErrs, err)
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseWithReason(reason string) error {
+	m.mx.Lock()
+	m.calls["CloseWithReason"]++
+	if !m.isClosing() && m.closeReason == "" {
+		m.closeReason = reason
+	}
+	m.mx.Unlock()
+
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosedByParent() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ClosedByParent"]++
+	return m.closedByParent
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseReasonText() string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloseReasonText"]++
+	return m.closeReason
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseStatus() (reason string, err error, duration time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloseStatus"]++
+	if !m.isClosed() {
+		return "", nil, 0
+	}
+	return m.closeReason, m.closeErr, m.closeDuration()
+}
+
+// Implements the closer.Closer interface.
+// The mock only distinguishes direct closes from closes by its parent.
+func (m *Mock) InitiatedBy() (closer.Origin, closer.Closer) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["InitiatedBy"]++
+	switch {
+	case !m.isClosing():
+		return closer.OriginNone, nil
+	case m.closedByParent && m.parent != nil:
+		return closer.OriginParent, m.parent
+	case m.closedByParent:
+		return closer.OriginParent, nil
+	default:
+		return closer.OriginDirect, nil
+	}
+}
+
+// Implements the closer.Closer interface.
+// The mock does not link the closers.
+func (m *Mock) Link(other closer.Closer) {
+	m.record("Link")
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseWithErrAndDone(err error) {
+	m.record("CloseWithErrAndDone")
+	m.addError(err)
+	m.done()
+	_ = m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseAndDone() error {
+	m.record("CloseAndDone")
+	m.done()
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseAndDone_() {
+	m.record("CloseAndDone_")
+	m.done()
+	_ = m.close()
+}
+
+// Implements the closer.Closer interface.
+// The parent is closed synchronously.
+func (m *Mock) CloseParentOnly(err error) {
+	m.mx.Lock()
+	m.calls["CloseParentOnly"]++
+	parent := m.parent
+	m.mx.Unlock()
+
+	if parent == nil || parent.getClosing() {
+		return
+	}
+	parent.addError(err)
+	_ = parent.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseChildren() (err error) {
+	m.mx.Lock()
+	m.calls["CloseChildren"]++
+	if m.isClosing() {
+		m.mx.Unlock()
+		return closer.ErrClosing
+	}
+	children := m.children
+	m.children = nil
+	m.mx.Unlock()
+
+	for _, child := range children {
+		child.mx.Lock()
+		child.parent = nil
+		child.mx.Unlock()
+		err = errors.Join(err, child.closeByParent())
+	}
+	return err
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseExcept(keep ...closer.Closer) error {
+	m.record("CloseExcept")
+
+	for _, k := range keep {
+		km, ok := k.(*Mock)
+		if !ok || !km.isDescendantOf(m) {
+			continue
+		}
+		if p := km.getParent(); p != nil {
+			km.mx.Lock()
+			km.parent = nil
+			km.mx.Unlock()
+			p.removeChild(km)
+		}
+	}
+	return m.close()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseSiblings() (err error) {
+	m.record("CloseSiblings")
+	p := m.getParent()
+	if p == nil {
+		return nil
+	}
+
+	p.mx.Lock()
+	if p.isClosing() {
+		p.mx.Unlock()
+		return closer.ErrClosing
+	}
+	var siblings, children []*Mock
+	for _, child := range p.children {
+		if child == m {
+			children = append(children, child)
+		} else {
+			siblings = append(siblings, child)
+		}
+	}
+	p.children = children
+	p.mx.Unlock()
+
+	for _, sibling := range siblings {
+		sibling.mx.Lock()
+		sibling.parent = nil
+		sibling.mx.Unlock()
+		err = errors.Join(err, sibling.closeByParent())
+	}
+	return err
+}
+
+// Implements the closer.Closer interface.
+// The dependency is only recorded, see CloseDeps().
+func (m *Mock) CloseAfter(other closer.Closer, timeout time.Duration) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloseAfter"]++
+	if other == closer.Closer(m) {
+		return closer.ErrCycle
+	} else if m.isClosing() {
+		return closer.ErrClosing
+	}
+	m.closeDeps = append(m.closeDeps, other)
+	return nil
+}
+
+//##################//
+//### Wait Group ###//
+//##################//
+
+// Implements the closer.Closer interface.
+// Close() does not wait for the wait group.
+func (m *Mock) CloserAddWait(delta int) {
+	m.mx.Lock()
+	m.calls["CloserAddWait"]++
+	m.waits += delta
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloserDone() {
+	m.record("CloserDone")
+	m.done()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) PendingWaitStacks() []string {
+	m.record("PendingWaitStacks")
+	return nil
+}
+
+//################//
+//### Children ###//
+//################//
+
+// Implements the closer.Closer interface.
+// The options are ignored.
+func (m *Mock) CloserOneWay(opts ...closer.Option) closer.Closer {
+	m.record("CloserOneWay")
+	return m.addChild()
+}
+
+// Implements the closer.Closer interface.
+// The options are ignored and the child does not close the mock.
+func (m *Mock) CloserTwoWay(opts ...closer.Option) closer.Closer {
+	m.record("CloserTwoWay")
+	return m.addChild()
+}
+
+// Implements the closer.Closer interface.
+// The predicate and options are ignored and the child does not close the mock.
+func (m *Mock) CloserTwoWayIf(predicate func(child closer.Closer) bool, opts ...closer.Option) closer.Closer {
+	m.record("CloserTwoWayIf")
+	return m.addChild()
+}
+
+// Implements the closer.Closer interface.
+// The clone is a new Mock with the same name, labels and close timeout.
+func (m *Mock) Clone() closer.Closer {
+	clone := NewMock()
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Clone"]++
+	clone.name = m.name
+	clone.closeTimeout = m.closeTimeout
+	if m.labels != nil {
+		clone.labels = make(map[string]string, len(m.labels))
+		for k, v := range m.labels {
+			clone.labels[k] = v
+		}
+	}
+	return clone
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) SetDeadline(t time.Time) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SetDeadline"]++
+	if m.isClosing() {
+		return closer.ErrClosing
+	}
+	m.deadline = t
+	return nil
+}
+
+// Implements the closer.Closer interface.
+// The timeout is only recorded, see CloseTimeout().
+func (m *Mock) SetCloseTimeout(d time.Duration) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SetCloseTimeout"]++
+	if m.isClosing() {
+		return closer.ErrClosing
+	}
+	m.closeTimeout = d
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnChildAdded(f func(child closer.Closer)) {
+	m.mx.Lock()
+	m.calls["OnChildAdded"]++
+	m.childAddedFuncs = append(m.childAddedFuncs, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnChildRemoved(f func(child closer.Closer)) {
+	m.mx.Lock()
+	m.calls["OnChildRemoved"]++
+	m.childRemovedFuncs = append(m.childRemovedFuncs, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) SuspendTwoWay() {
+	m.record("SuspendTwoWay")
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ResumeTwoWay() {
+	m.record("ResumeTwoWay")
+}
+
+// Implements the closer.Closer interface.
+// Children of a Mock never close their parent, hence only the closing state is checked.
+func (m *Mock) SetTwoWay(twoWay bool) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SetTwoWay"]++
+	if m.isClosing() {
+		return closer.ErrClosing
+	}
+	return nil
+}
+
+// Implements the closer.Closer interface.
+// Only Mocks can be adopted. Like a real closer, the children of the other
+// mock are moved to this mock and the other mock is left without children.
+func (m *Mock) Adopt(other closer.Closer) error {
+	m.record("Adopt")
+
+	o, ok := other.(*Mock)
+	if !ok {
+		return closer.ErrUnknownCloser
+	}
+	for p := m; p != nil; p = p.getParent() {
+		if p == o {
+			return closer.ErrCycle
+		}
+	}
+	if m.getClosing() || o.getClosing() {
+		return closer.ErrClosing
+	}
+
+	o.mx.Lock()
+	removed := o.children
+	o.children = nil
+	removedFuncs := o.childRemovedFuncs
+	o.mx.Unlock()
+
+	// Keep the order of the children.
+	adopted := make([]*Mock, 0, len(removed))
+	for _, child := range removed {
+		child.mx.Lock()
+		if !child.isClosed() {
+			child.parent = m
+			adopted = append(adopted, child)
+		}
+		child.mx.Unlock()
+	}
+
+	m.mx.Lock()
+	m.children = append(m.children, adopted...)
+	addedFuncs := m.childAddedFuncs
+	m.mx.Unlock()
+
+	for _, child := range removed {
+		for _, f := range removedFuncs {
+			f(child)
+		}
+	}
+	for _, child := range adopted {
+		for _, f := range addedFuncs {
+			f(child)
+		}
+	}
+	return nil
+}
+
+//###############//
+//### Context ###//
+//###############//
+
+// Implements the closer.Closer interface.
+func (m *Mock) Context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Context"]++
+	if m.isClosing() {
+		cancel(m.closeCause())
+	} else {
+		m.cancels = append(m.cancels, cancel)
+	}
+	return ctx, func() { cancel(nil) }
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) WaitGroupContext() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["WaitGroupContext"]++
+	if m.isClosing() {
+		cancel(m.closeCause())
+	} else {
+		m.cancels = append(m.cancels, cancel)
+	}
+	return ctx
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosedContext() context.Context {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ClosedContext"]++
+	if m.isClosed() {
+		cancel(m.closeCause())
+	} else {
+		m.closedCancels = append(m.closedCancels, cancel)
+	}
+	return ctx
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseOnContextDone(ctx context.Context) {
+	m.record("CloseOnContextDone")
+
+	go func() {
+		select {
+		case <-m.closingChan:
+		case <-ctx.Done():
+			_ = m.close()
+		}
+	}()
+}
+
+// Implements the closer.Closer interface.
+// The mock watches the context within its own goroutine.
+func (m *Mock) BindContextPoll(ctx context.Context) {
+	m.record("BindContextPoll")
+
+	go func() {
+		select {
+		case <-m.closingChan:
+		case <-ctx.Done():
+			_ = m.close()
+		}
+	}()
+}
+
+//####################//
+//### Soft Closing ###//
+//####################//
+
+// Implements the closer.Closer interface.
+// The closing funcs are not executed.
+func (m *Mock) BeginClosing() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["BeginClosing"]++
+	if m.isClosing() {
+		return closer.ErrClosing
+	}
+	select {
+	case <-m.softClosingChan:
+	default:
+		close(m.softClosingChan)
+	}
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) AbortClosing() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["AbortClosing"]++
+	if m.isClosing() {
+		return closer.ErrClosing
+	}
+	select {
+	case <-m.softClosingChan:
+		m.softClosingChan = make(chan struct{})
+	default:
+	}
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) SoftClosingChan() <-chan struct{} {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SoftClosingChan"]++
+	return m.softClosingChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosingChan() <-chan struct{} {
+	m.record("ClosingChan")
+	return m.closingChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Pause() {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Pause"]++
+	if m.paused || m.isClosing() {
+		return
+	}
+	m.paused = true
+	if m.pausedChan != nil {
+		close(m.pausedChan)
+	}
+	m.resumedChan = nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Resume() {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Resume"]++
+	if !m.paused {
+		return
+	}
+	m.paused = false
+	if m.resumedChan != nil {
+		close(m.resumedChan)
+	}
+	m.pausedChan = nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) PausedChan() <-chan struct{} {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["PausedChan"]++
+	if m.pausedChan == nil {
+		m.pausedChan = make(chan struct{})
+		if m.paused {
+			close(m.pausedChan)
+		}
+	}
+	return m.pausedChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ResumedChan() <-chan struct{} {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ResumedChan"]++
+	if m.resumedChan == nil {
+		m.resumedChan = make(chan struct{})
+		if !m.paused {
+			close(m.resumedChan)
+		}
+	}
+	return m.resumedChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnStopCh() <-chan struct{} {
+	m.record("OnStopCh")
+	return m.closingChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosedChan() <-chan struct{} {
+	m.record("ClosedChan")
+	return m.closedChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) IsClosing() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["IsClosing"]++
+	return m.isClosing()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) IsClosed() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["IsClosed"]++
+	return m.isClosed()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) IsCloseInProgress() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["IsCloseInProgress"]++
+	return m.isClosing() && !m.isClosed()
+}
+
+//#############//
+//### Funcs ###//
+//#############//
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnClose(f ...closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnClose"]++
+	m.closeFuncs = append(m.closeFuncs, f...)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseCtx(f ...closer.CloseCtxFunc) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnCloseCtx"]++
+	for _, ff := range f {
+		ff := ff
+		m.closeFuncs = append(m.closeFuncs, func() error {
+			return ff(m.getCloseCtx())
+		})
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Apply(set *closer.CloseFuncSet) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Apply"]++
+	m.closeFuncs = append(m.closeFuncs, set.Funcs()...)
+}
+
+// Implements the closer.Closer interface.
+// The io.Closers are always accounted, see ResourceReport().
+func (m *Mock) OnCloseCloser(closers ...io.Closer) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnCloseCloser"]++
+	if m.resourceStats == nil {
+		m.resourceStats = make(map[string]*closer.ResourceStat)
+	}
+	for _, cl := range closers {
+		typ := closer.ResourceType(cl)
+		s := m.resourceStats[typ]
+		if s == nil {
+			s = &closer.ResourceStat{}
+			m.resourceStats[typ] = s
+		}
+		s.Registered++
+
+		closeFunc := cl.Close
+		m.closeFuncs = append(m.closeFuncs, func() error {
+			err := callCloseFunc(closeFunc)
+
+			m.mx.Lock()
+			if err != nil {
+				s.Failed++
+			} else {
+				s.Closed++
+			}
+			m.mx.Unlock()
+			return err
+		})
+	}
+}
+
+// Implements the closer.Closer interface.
+// In contrast to a real closer, the report is never nil.
+func (m *Mock) ResourceReport() map[string]closer.ResourceStat {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ResourceReport"]++
+	report := make(map[string]closer.ResourceStat, len(m.resourceStats))
+	for typ, s := range m.resourceStats {
+		report[typ] = *s
+	}
+	return report
+}
+
+// Implements the closer.Closer interface.
+// The mock does not validate the closing order, the func is only recorded.
+func (m *Mock) OnCloseNeeds(f closer.CloseFunc, needs ...closer.Closer) {
+	m.mx.Lock()
+	m.calls["OnCloseNeeds"]++
+	m.closeFuncs = append(m.closeFuncs, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseBenign(f ...closer.CloseFunc) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnCloseBenign"]++
+	for _, ff := range f {
+		ff := ff
+		m.closeFuncs = append(m.closeFuncs, func() error {
+			return closer.Benign(ff())
+		})
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnClosingCtx(f ...closer.CloseCtxFunc) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnClosingCtx"]++
+	for _, ff := range f {
+		ff := ff
+		m.closingFuncs = append(m.closingFuncs, func() error {
+			return ff(m.getCloseCtx())
+		})
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseDep(f closer.CloseFunc, after ...closer.CloseFuncHandle) closer.CloseFuncHandle {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnCloseDep"]++
+	h := closer.CloseFuncHandle(len(m.closeFuncs))
+	m.closeFuncs = append(m.closeFuncs, f)
+	m.addCloseFuncDeps(h, after)
+	return h
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) AddCloseDep(h closer.CloseFuncHandle, after ...closer.CloseFuncHandle) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["AddCloseDep"]++
+	m.addCloseFuncDeps(h, after)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Phase(name string) closer.ClosePhase {
+	m.record("Phase")
+	return mockPhase{m: m, name: name}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) SetPhaseOrder(names ...string) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SetPhaseOrder"]++
+	m.phaseOrder = append([]string(nil), names...)
+}
+
+// A mockPhase implements the closer.ClosePhase interface for a Mock.
+type mockPhase struct {
+	m    *Mock
+	name string
+}
+
+// Implements the closer.ClosePhase interface.
+func (p mockPhase) OnClose(f ...closer.CloseFunc) {
+	m := p.m
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.closeFuncPhases == nil {
+		m.closeFuncPhases = make(map[int]string)
+	}
+	for _, ff := range f {
+		m.closeFuncPhases[len(m.closeFuncs)] = p.name
+		m.closeFuncs = append(m.closeFuncs, ff)
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseBroadcast(ch chan<- struct{}) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["OnCloseBroadcast"]++
+	if m.isClosing() {
+		notify(ch)
+		return
+	}
+	m.broadcastChans = append(m.broadcastChans, ch)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) DoOnClosed(f func()) {
+	m.record("DoOnClosed")
+	m.doOnClosed(f)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseErr(f func(err error)) {
+	m.record("OnCloseErr")
+	m.doOnClosed(func() {
+		m.mx.Lock()
+		err := m.closeErr
+		m.mx.Unlock()
+		f(err)
+	})
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseErrTo(ch chan<- error) {
+	m.record("CloseErrTo")
+	if ch == nil {
+		return
+	}
+	m.doOnClosed(func() {
+		m.mx.Lock()
+		err := m.closeErr
+		m.mx.Unlock()
+
+		select {
+		case ch <- err:
+		default:
+		}
+	})
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) SetFinalizer(f func(err error)) {
+	m.mx.Lock()
+	m.calls["SetFinalizer"]++
+	if !m.isClosed() {
+		m.finalizer = f
+		m.mx.Unlock()
+		return
+	}
+	err := m.closeErr
+	m.mx.Unlock()
+
+	f(err)
+}
+
+// Implements the closer.Closer interface.
+// The timeout is not enforced.
+func (m *Mock) OnCloseTimeout(d time.Duration, f closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnCloseTimeout"]++
+	m.closeFuncs = append(m.closeFuncs, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Defer(f func()) {
+	m.mx.Lock()
+	m.calls["Defer"]++
+	m.closeFuncs = append(m.closeFuncs, func() error {
+		f()
+		return nil
+	})
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseOnce(f closer.CloseFunc) closer.CloseFunc {
+	var once sync.Once
+	var err error
+	g := func() error {
+		once.Do(func() { err = f() })
+		return err
+	}
+
+	m.mx.Lock()
+	m.calls["OnCloseOnce"]++
+	m.closeFuncs = append(m.closeFuncs, g)
+	m.mx.Unlock()
+	return g
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseIf(active *atomic.Bool, f closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnCloseIf"]++
+	m.closeFuncs = append(m.closeFuncs, func() error {
+		if !active.Load() {
+			return nil
+		}
+		return f()
+	})
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Manage(start func() error, stop closer.CloseFunc) error {
+	m.mx.Lock()
+	m.calls["Manage"]++
+	closing := m.isClosing()
+	m.mx.Unlock()
+
+	if closing {
+		return closer.ErrClosing
+	}
+	if err := start(); err != nil {
+		return err
+	}
+
+	m.mx.Lock()
+	m.closeFuncs = append(m.closeFuncs, stop)
+	m.mx.Unlock()
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseFinal(f ...closer.FinalFunc) {
+	m.mx.Lock()
+	m.calls["OnCloseFinal"]++
+	m.finalFuncs = append(m.finalFuncs, f...)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnCloseLast(f ...closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnCloseLast"]++
+	m.lastFuncs = append(m.lastFuncs, f...)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnClosing(f ...closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnClosing"]++
+	m.closingFuncs = append(m.closingFuncs, f...)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OnBeforeChildrenClose(f ...closer.CloseFunc) {
+	m.mx.Lock()
+	m.calls["OnBeforeChildrenClose"]++
+	m.beforeChildrenFuncs = append(m.beforeChildrenFuncs, f...)
+	m.mx.Unlock()
+}
+
+//#####################//
+//### Introspection ###//
+//#####################//
+
+// Implements the closer.Closer interface.
+func (m *Mock) State() closer.State {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["State"]++
+	return m.state()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) NumChildren() int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["NumChildren"]++
+	return len(m.children)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ForEachChild(fn func(child closer.Closer) bool) {
+	m.mx.Lock()
+	m.calls["ForEachChild"]++
+	children := append([]*Mock(nil), m.children...)
+	m.mx.Unlock()
+
+	for _, child := range children {
+		if !fn(child) {
+			return
+		}
+	}
+}
+
+// Implements the closer.Closer interface.
+// See ChildMocks() for the children as Mocks.
+func (m *Mock) Children() []closer.Closer {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Children"]++
+	children := make([]closer.Closer, len(m.children))
+	for i, child := range m.children {
+		children[i] = child
+	}
+	return children
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) PendingWaits() int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["PendingWaits"]++
+	return m.waits
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) WouldBlock() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["WouldBlock"]++
+	return m.waits > 0 || len(m.children) > 0
+}
+
+// Implements the closer.Closer interface.
+// Recorded routines are not counted, because they are not executed by the mock.
+func (m *Mock) ManagedGoroutines() int {
+	m.mx.Lock()
+	m.calls["ManagedGoroutines"]++
+	n := m.waits
+	m.mx.Unlock()
+
+	for _, child := range m.ChildMocks() {
+		n += child.ManagedGoroutines()
+	}
+	return n
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CountOpen() int {
+	m.mx.Lock()
+	m.calls["CountOpen"]++
+	closed := m.isClosed()
+	m.mx.Unlock()
+
+	if closed {
+		return 0
+	}
+	n := 1
+	for _, child := range m.ChildMocks() {
+		n += child.CountOpen()
+	}
+	return n
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseDuration() time.Duration {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloseDuration"]++
+	return m.closeDuration()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Stats() closer.Stats {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Stats"]++
+	return m.stats()
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) MarshalJSON() ([]byte, error) {
+	m.mx.Lock()
+	m.calls["MarshalJSON"]++
+	s := m.stats()
+	m.mx.Unlock()
+
+	v := struct {
+		Name         string     `json:"name"`
+		State        string     `json:"state"`
+		Children     int        `json:"children"`
+		PendingWaits int        `json:"pendingWaits"`
+		ClosedAt     *time.Time `json:"closedAt,omitempty"`
+		Reason       string     `json:"reason,omitempty"`
+	}{
+		Name:         s.Name,
+		State:        s.State.String(),
+		Children:     s.NumChildren,
+		PendingWaits: s.PendingWaits,
+		Reason:       s.CloseReason,
+	}
+	if !s.ClosedAt.IsZero() {
+		v.ClosedAt = &s.ClosedAt
+	}
+	return json.Marshal(v)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) OpenLeaves() []closer.Closer {
+	m.record("OpenLeaves")
+	leaves, _ := m.appendOpenLeaves(nil)
+	return leaves
+}
+
+// Implements the closer.Closer interface.
+// The mock does not track the steps of its closing order and always returns nil.
+func (m *Mock) BlockingReasons() []string {
+	m.record("BlockingReasons")
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloseProgress() (done, total int) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloseProgress"]++
+	total = len(m.closingFuncs) + len(m.beforeChildrenFuncs) + len(m.children) + len(m.closeFuncs) + len(m.finalFuncs) + len(m.lastFuncs)
+	return 0, total
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ClosePlan() []closer.PlanStep {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["ClosePlan"]++
+
+	var plan []closer.PlanStep
+	for i := len(m.closingFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseClosing, Name: funcName(m.closingFuncs[i])})
+	}
+	for i := len(m.beforeChildrenFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseBeforeChildren, Name: funcName(m.beforeChildrenFuncs[i])})
+	}
+	for _, child := range m.children {
+		// Lock order: parent before child.
+		child.mx.Lock()
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseChildren, Name: child.name})
+		child.mx.Unlock()
+	}
+	order, _ := orderCloseFuncs(len(m.closeFuncs), m.closeFuncDeps)
+	order = orderPhases(order, m.closeFuncPhases, m.phaseOrder)
+	for _, i := range order {
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseClose, Name: funcName(m.closeFuncs[i])})
+	}
+	for i := len(m.finalFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseFinal, Name: funcName(m.finalFuncs[i])})
+	}
+	for i := len(m.lastFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, closer.PlanStep{Phase: closer.PhaseLast, Name: funcName(m.lastFuncs[i])})
+	}
+	return plan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) FuncCounts() map[closer.Phase]int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["FuncCounts"]++
+	return map[closer.Phase]int{
+		closer.PhaseClosing:        len(m.closingFuncs),
+		closer.PhaseBeforeChildren: len(m.beforeChildrenFuncs),
+		closer.PhaseClose:          len(m.closeFuncs),
+		closer.PhaseFinal:          len(m.finalFuncs),
+		closer.PhaseLast:           len(m.lastFuncs),
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Name() string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Name"]++
+	return m.name
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) Labels() map[string]string {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["Labels"]++
+	if m.labels == nil {
+		return nil
+	}
+	labels := make(map[string]string, len(m.labels))
+	for k, v := range m.labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ID() uint64 {
+	m.record("ID")
+	return m.id
+}
+
+//###############//
+//### Waiting ###//
+//###############//
+
+// Implements the closer.Closer interface.
+func (m *Mock) SevereError() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["SevereError"]++
+	if !m.isClosed() {
+		return nil
+	}
+	return closer.FilterBenign(m.closeErr)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloserError() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.calls["CloserError"]++
+	if !m.isClosed() {
+		return nil
+	}
+	return m.closeErr
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloserWait(ctx context.Context) error {
+	m.record("CloserWait")
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.closedChan:
+		m.mx.Lock()
+		defer m.mx.Unlock()
+		return m.closeErr
+	}
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) CloserWaitChan(ctx context.Context) <-chan error {
+	waitChan := make(chan error, 1)
+	go func() {
+		waitChan <- m.CloserWait(ctx)
+	}()
+	return waitChan
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) AwaitChildrenClosed(ctx context.Context) error {
+	m.record("AwaitChildrenClosed")
+
+	for _, child := range m.ChildMocks() {
+		select {
+		case <-child.closedChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) WaitChild(ctx context.Context, name string) error {
+	m.record("WaitChild")
+
+	child := m.childByName(name)
+	if child == nil {
+		return closer.ErrChildNotFound
+	}
+	return child.CloserWait(ctx)
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) ChildByName(name string) (closer.Closer, bool) {
+	m.record("ChildByName")
+
+	child := m.childByName(name)
+	if child == nil {
+		return nil, false
+	}
+	return child, true
+}
+
+// Implements the closer.Closer interface.
+func (m *Mock) IsDescendantOf(ancestor closer.Closer) bool {
+	m.record("IsDescendantOf")
+
+	a, ok := ancestor.(*Mock)
+	return ok && m.isDescendantOf(a)
+}
+
+// childByName returns the first child with the given name or nil, if none is found.
+func (m *Mock) childByName(name string) *Mock {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	for _, child := range m.children {
+		// Lock order: parent before child.
+		child.mx.Lock()
+		found := child.name == name
+		child.mx.Unlock()
+		if found {
+			return child
+		}
+	}
+	return nil
+}
+
+// Implements the closer.Closer interface.
+// The func is executed synchronously.
+func (m *Mock) BlockCloser(f func() error) error {
+	m.record("BlockCloser")
+
+	if m.getClosing() {
+		return closer.ErrClosed
+	}
+	return f()
+}
+
+// Implements the closer.Closer interface.
+// The func is only recorded, see Routines().
+func (m *Mock) RunCloserRoutine(f func() error) {
+	m.mx.Lock()
+	m.calls["RunCloserRoutine"]++
+	m.routines = append(m.routines, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+// The func is only recorded, see Routines().
+func (m *Mock) RunEvery(d time.Duration, f func() error) {
+	m.mx.Lock()
+	m.calls["RunEvery"]++
+	m.routines = append(m.routines, f)
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+// The func is only recorded, see Routines(). The recorded routine returns
+// an error wrapping closer.ErrPanic, if f panics.
+func (m *Mock) SafeGo(f func()) {
+	m.mx.Lock()
+	m.calls["SafeGo"]++
+	m.routines = append(m.routines, func() error {
+		return callCloseFunc(func() error {
+			f()
+			return nil
+		})
+	})
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+// Each worker is only recorded as a routine with the closing chan of the mock,
+// see Routines(). The recorded routine returns an error wrapping closer.ErrPanic,
+// if the worker panics.
+func (m *Mock) SpawnWorkers(n int, worker func(stop <-chan struct{})) {
+	m.mx.Lock()
+	m.calls["SpawnWorkers"]++
+	for i := 0; i < n; i++ {
+		m.routines = append(m.routines, func() error {
+			return callCloseFunc(func() error {
+				worker(m.closingChan)
+				return nil
+			})
+		})
+	}
+	m.mx.Unlock()
+}
+
+// Implements the closer.Closer interface.
+// Each worker is only recorded as a routine with the closing chan of the mock,
+// see Routines().
+func (m *Mock) SpawnWorkersErr(n int, worker func(stop <-chan struct{}) error) {
+	m.mx.Lock()
+	m.calls["SpawnWorkersErr"]++
+	for i := 0; i < n; i++ {
+		m.routines = append(m.routines, func() error {
+			return callCloseFunc(func() error {
+				return worker(m.closingChan)
+			})
+		})
+	}
+	m.mx.Unlock()
+}
+
+//###############//
+//### Private ###//
+//###############//
+
+func (m *Mock) record(method string) {
+	m.mx.Lock()
+	m.calls[method]++
+	m.mx.Unlock()
+}
+
+// close executes the closing order of a real closer synchronously,
+// but does not wait for the wait group or close dependencies.
+func (m *Mock) close() error {
+	m.mx.Lock()
+	if m.closeStarted || m.isClosed() {
+		m.mx.Unlock()
+		<-m.closedChan
+		return m.closeErr
+	}
+	m.closeStarted = true
+	m.setClosing()
+	var (
+		closingFuncs        = m.closingFuncs
+		beforeChildrenFuncs = m.beforeChildrenFuncs
+		closeFuncs          = m.closeFuncs
+		closeFuncDeps       = m.closeFuncDeps
+		closeFuncPhases     = m.closeFuncPhases
+		phaseOrder          = m.phaseOrder
+		finalFuncs          = m.finalFuncs
+		lastFuncs           = m.lastFuncs
+		children            = m.children
+	)
+	m.closingFuncs = nil
+	m.beforeChildrenFuncs = nil
+	m.closeFuncs = nil
+	m.closeFuncDeps = nil
+	m.closeFuncPhases = nil
+	m.finalFuncs = nil
+	m.lastFuncs = nil
+	m.children = nil
+	m.mx.Unlock()
+
+	var (
+		closeErrs, ownErrs []error
+		firstErr           error
+	)
+	addErr := func(err error) {
+		if err != nil {
+			closeErrs = append(closeErrs, err)
+			ownErrs = append(ownErrs, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	var steps []closer.StepResult
+	step := func(phase closer.Phase, name string, start time.Time, err error) error {
+		steps = append(steps, closer.StepResult{Phase: phase, Name: name, Err: err, Duration: time.Since(start)})
+		return err
+	}
+
+	for i := len(closingFuncs) - 1; i >= 0; i-- {
+		start := time.Now()
+		addErr(step(closer.PhaseClosing, funcName(closingFuncs[i]), start, callCloseFunc(closingFuncs[i])))
+	}
+	for i := len(beforeChildrenFuncs) - 1; i >= 0; i-- {
+		start := time.Now()
+		addErr(step(closer.PhaseBeforeChildren, funcName(beforeChildrenFuncs[i]), start, callCloseFunc(beforeChildrenFuncs[i])))
+	}
+	for _, child := range children {
+		child.mx.Lock()
+		name := child.name
+		child.mx.Unlock()
+		if err := step(closer.PhaseChildren, name, time.Now(), child.closeByParent()); err != nil {
+			closeErrs = append(closeErrs, err)
+			if firstErr == nil {
+				firstErr = child.getFirstErr()
+			}
+		}
+	}
+	order, cycle := orderCloseFuncs(len(closeFuncs), closeFuncDeps)
+	order = orderPhases(order, closeFuncPhases, phaseOrder)
+	if cycle {
+		addErr(closer.ErrCloseFuncCycle)
+	}
+	for _, i := range order {
+		start := time.Now()
+		addErr(step(closer.PhaseClose, funcName(closeFuncs[i]), start, callCloseFunc(closeFuncs[i])))
+	}
+	for i := len(finalFuncs) - 1; i >= 0; i-- {
+		m.mx.Lock()
+		errs := closeErrs
+		if m.closeErr != nil {
+			errs = append([]error{m.closeErr}, closeErrs...)
+		}
+		m.mx.Unlock()
+		f, start := finalFuncs[i], time.Now()
+		addErr(step(closer.PhaseFinal, funcName(f), start, callCloseFunc(func() error {
+			return f(append([]error(nil), errs...))
+		})))
+	}
+	for i := len(lastFuncs) - 1; i >= 0; i-- {
+		start := time.Now()
+		addErr(step(closer.PhaseLast, funcName(lastFuncs[i]), start, callCloseFunc(lastFuncs[i])))
+	}
+
+	m.mx.Lock()
+	if m.firstErr == nil {
+		m.firstErr = firstErr
+	}
+	if m.firstErr == nil {
+		m.firstErr = m.closeErr
+	}
+	m.ownErr = errors.Join(m.closeErr, errors.Join(ownErrs...), errors.Join(m.appendedErrs...))
+	m.closeErr = errors.Join(m.closeErr, errors.Join(closeErrs...), errors.Join(m.appendedErrs...))
+	m.appendedErrs = nil
+	m.closedChildren = children
+	m.stepResults = steps
+	closedFuncs := m.setClosed()
+	parent := m.parent
+	err := m.closeErr
+	m.mx.Unlock()
+
+	for _, f := range closedFuncs {
+		callClosedFunc(f)
+	}
+
+	if parent != nil && !parent.getClosing() {
+		parent.removeChild(m)
+	}
+	return err
+}
+
+// closeByParent closes the mock on behalf of its parent.
+func (m *Mock) closeByParent() error {
+	m.mx.Lock()
+	if !m.isClosing() {
+		m.closedByParent = true
+	}
+	m.mx.Unlock()
+
+	return m.close()
+}
+
+// getCloseCtx returns the context passed to CloseCtx() or a context that is never done.
+func (m *Mock) getCloseCtx() context.Context {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.closeCtx == nil {
+		return context.Background()
+	}
+	return m.closeCtx
+}
+
+// closeBottomUp implements CloseBottomUp() without recording the call.
+func (m *Mock) closeBottomUp(byParent bool) error {
+	m.mx.Lock()
+	var children []*Mock
+	if !m.isClosing() {
+		children = m.children
+		m.children = nil
+	}
+	m.mx.Unlock()
+
+	var errs []error
+	for _, child := range children {
+		child.mx.Lock()
+		child.parent = nil
+		child.mx.Unlock()
+		errs = append(errs, child.closeBottomUp(true))
+	}
+	if byParent {
+		return errors.Join(append(errs, m.closeByParent())...)
+	}
+	return errors.Join(append(errs, m.close())...)
+}
+
+// setClosing closes the closing chan. The mutex must be locked.
+func (m *Mock) setClosing() {
+	if m.isClosing() {
+		return
+	}
+	m.closingAt = time.Now()
+	close(m.closingChan)
+	for _, cancel := range m.cancels {
+		cancel(m.closeCause())
+	}
+	m.cancels = nil
+	for _, ch := range m.broadcastChans {
+		notify(ch)
+	}
+	m.broadcastChans = nil
+}
+
+// notify sends to the channel without blocking.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// doOnClosed implements DoOnClosed() without recording the call.
+func (m *Mock) doOnClosed(f func()) {
+	m.mx.Lock()
+	if !m.isClosed() {
+		m.closedFuncs = append(m.closedFuncs, f)
+		m.mx.Unlock()
+		return
+	}
+	m.mx.Unlock()
+
+	callClosedFunc(f)
+}
+
+// callClosedFunc calls the given func of DoOnClosed() and discards a potential panic
+// like the closer. In contrast to the closer, the mock calls the funcs synchronously,
+// so that tests can assert their effects right after the close.
+func callClosedFunc(f func()) {
+	defer func() {
+		_ = recover()
+	}()
+	f()
+}
+
+// setClosed closes the closed chan and returns the funcs of DoOnClosed(),
+// which must be called without the mutex locked. The mutex must be locked.
+func (m *Mock) setClosed() []func() {
+	m.closedAt = time.Now()
+	if m.finalizer != nil {
+		if err := callCloseFunc(func() error { m.finalizer(m.closeErr); return nil }); err != nil {
+			m.ownErr = errors.Join(m.ownErr, err)
+			m.closeErr = errors.Join(m.closeErr, err)
+		}
+		m.finalizer = nil
+	}
+	close(m.closedChan)
+	for _, cancel := range m.closedCancels {
+		cancel(m.closeCause())
+	}
+	m.closedCancels = nil
+
+	closedFuncs := m.closedFuncs
+	m.closedFuncs = nil
+	return closedFuncs
+}
+
+// closeCause returns the cause of the canceled contexts like a real closer.
+// The mutex must be locked.
+func (m *Mock) closeCause() error {
+	if m.closeErr == nil {
+		return closer.ErrClosed
+	}
+	return m.closeErr
+}
+
+func (m *Mock) isClosing() bool {
+	select {
+	case <-m.closingChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Mock) isClosed() bool {
+	select {
+	case <-m.closedChan:
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *Mock) state() closer.State {
+	if m.isClosed() {
+		return closer.StateClosed
+	} else if m.isClosing() {
+		return closer.StateClosing
+	}
+	return closer.StateOpen
+}
+
+func (m *Mock) closeDuration() time.Duration {
+	if m.closingAt.IsZero() {
+		return 0
+	} else if m.closedAt.IsZero() {
+		return time.Since(m.closingAt)
+	}
+	return m.closedAt.Sub(m.closingAt)
+}
+
+func (m *Mock) addError(err error) {
+	m.mx.Lock()
+	if !m.isClosed() {
+		m.closeErr = errors.Join(m.closeErr, err)
+		if err != nil && m.firstErr == nil && !m.isClosing() {
+			m.firstErr = err
+		}
+	}
+	m.mx.Unlock()
+}
+
+func (m *Mock) getFirstErr() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if m.firstErr == nil {
+		return m.closeErr
+	}
+	return m.firstErr
+}
+
+func (m *Mock) done() {
+	m.mx.Lock()
+	m.waits--
+	m.mx.Unlock()
+}
+
+// getClosing returns whether the mock is closing without recording a call.
+func (m *Mock) getClosing() bool {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.isClosing()
+}
+
+// stats returns the stats of the mock. The mutex must be locked.
+func (m *Mock) stats() closer.Stats {
+	return closer.Stats{
+		State:         m.state(),
+		NumChildren:   len(m.children),
+		NumCloseFuncs: len(m.closeFuncs),
+		PendingWaits:  m.waits,
+		CloseDuration: m.closeDuration(),
+		ClosedAt:      m.closedAt,
+		Name:          m.name,
+		CloseReason:   m.closeReason,
+	}
+}
+
+func (m *Mock) getParent() *Mock {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	return m.parent
+}
+
+func (m *Mock) isDescendantOf(ancestor *Mock) bool {
+	for p := m.getParent(); p != nil; p = p.getParent() {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mock) addChild() *Mock {
+	child := NewMock()
+	child.parent = m
+
+	m.mx.Lock()
+	m.children = append(m.children, child)
+	funcs := m.childAddedFuncs
+	m.mx.Unlock()
+
+	for _, f := range funcs {
+		f(child)
+	}
+	return child
+}
+
+func (m *Mock) removeChild(child *Mock) {
+	m.mx.Lock()
+	found := false
+	for i, c := range m.children {
+		if c == child {
+			m.children = append(m.children[:i], m.children[i+1:]...)
+			found = true
+			break
+		}
+	}
+	funcs := m.childRemovedFuncs
+	m.mx.Unlock()
+
+	if !found {
+		return
+	}
+	for _, f := range funcs {
+		f(child)
+	}
+}
+
+func (m *Mock) treeResult() closer.TreeResult {
+	m.mx.Lock()
+	res := closer.TreeResult{
+		ID:       m.id,
+		Name:     m.name,
+		Err:      m.ownErr,
+		Duration: m.closeDuration(),
+	}
+	children := m.closedChildren
+	m.mx.Unlock()
+
+	for _, child := range children {
+		res.Children = append(res.Children, child.treeResult())
+	}
+	return res
+}
+
+func (m *Mock) appendOpenLeaves(leaves []closer.Closer) ([]closer.Closer, bool) {
+	m.mx.Lock()
+	closed := m.isClosed()
+	m.mx.Unlock()
+	if closed {
+		return leaves, false
+	}
+	hasOpenChild := false
+	for _, child := range m.ChildMocks() {
+		var open bool
+		leaves, open = child.appendOpenLeaves(leaves)
+		hasOpenChild = hasOpenChild || open
+	}
+	if !hasOpenChild {
+		leaves = append(leaves, m)
+	}
+	return leaves, true
+}
+
+// addCloseFuncDeps records that the func of h runs after the funcs of after.
+// The mutex must be locked.
+func (m *Mock) addCloseFuncDeps(h closer.CloseFuncHandle, after []closer.CloseFuncHandle) {
+	if len(after) == 0 {
+		return
+	}
+	if m.closeFuncDeps == nil {
+		m.closeFuncDeps = make(map[int][]int)
+	}
+	for _, a := range after {
+		m.closeFuncDeps[int(h)] = append(m.closeFuncDeps[int(h)], int(a))
+	}
+}
+
+// orderCloseFuncs returns the execution order of the close funcs like a real closer.
+func orderCloseFuncs(n int, deps map[int][]int) (order []int, cycle bool) {
+	order = make([]int, 0, n)
+	done := make([]bool, n)
+
+	ready := func(i int) bool {
+		for _, d := range deps[i] {
+			if d >= 0 && d < n && d != i && !done[d] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for len(order) < n {
+		next := -1
+		for i := n - 1; i >= 0; i-- {
+			if !done[i] && ready(i) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			for i := n - 1; i >= 0; i-- {
+				if !done[i] {
+					order = append(order, i)
+				}
+			}
+			return order, true
+		}
+		done[next] = true
+		order = append(order, next)
+	}
+	return order, false
+}
+
+// orderPhases sorts the execution order of the close funcs by their phases like a real closer.
+func orderPhases(order []int, phases map[int]string, phaseOrder []string) []int {
+	if len(phases) == 0 {
+		return order
+	}
+
+	rank := make(map[string]int, len(phaseOrder))
+	for _, name := range phaseOrder {
+		if _, ok := rank[name]; !ok {
+			rank[name] = len(rank) + 1
+		}
+	}
+	indexes := make([]int, 0, len(phases))
+	for i := range phases {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if _, ok := rank[phases[i]]; !ok {
+			rank[phases[i]] = len(rank) + 1
+		}
+	}
+
+	rankOf := func(i int) int {
+		name, ok := phases[i]
+		if !ok {
+			return 0
+		}
+		return rank[name]
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rankOf(order[a]) < rankOf(order[b])
+	})
+	return order
+}
+
+// callCloseFunc calls the given close func and recovers a potential panic
+// like a real closer.
+func callCloseFunc(f closer.CloseFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", closer.ErrPanic, r)
+		}
+	}()
+	return f()
+}
+
+// funcName returns the name of the given function.
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
@@ -0,0 +1,274 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closertest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	"github.com/desertbit/closer/v3/closertest"
+	r "github.com/stretchr/testify/require"
+)
+
+// A component that is tested with the mock.
+type component struct {
+	closed bool
+}
+
+func newComponent(c closer.Closer) *component {
+	comp := &component{}
+	c.CloserAddWait(1)
+	c.OnClose(func() error {
+		comp.closed = true
+		return nil
+	})
+	c.RunCloserRoutine(func() error {
+		defer c.CloserDone()
+		<-c.ClosingChan()
+		return nil
+	})
+	return comp
+}
+
+func TestMock_Record(t *testing.T) {
+	t.Parallel()
+
+	m := closertest.NewMock()
+	comp := newComponent(m)
+
+	r.Equal(t, 1, m.Calls("CloserAddWait"))
+	r.Equal(t, 1, m.Calls("OnClose"))
+	r.Equal(t, 1, m.Calls("RunCloserRoutine"))
+	r.Zero(t, m.Calls("Close"))
+	r.Len(t, m.CloseFuncs(), 1)
+	r.Len(t, m.Routines(), 1)
+	r.Equal(t, 1, m.PendingWaits())
+	r.False(t, comp.closed)
+
+	// The routine is executed by the test.
+	routine := m.Routines()[0]
+	done := make(chan error)
+	go func() { done <- routine() }()
+
+	m.SimulateClosing()
+	r.NoError(t, <-done)
+	r.True(t, m.IsClosing())
+	r.False(t, m.IsClosed())
+	r.Zero(t, m.PendingWaits())
+
+	// SimulateClosing does not execute the funcs.
+	r.False(t, comp.closed)
+	r.NoError(t, m.Close())
+	r.True(t, comp.closed)
+	r.True(t, m.IsClosed())
+	r.Equal(t, 1, m.Calls("Close"))
+	r.Empty(t, m.CloseFuncs())
+}
+
+func TestMock_SimulateClosed(t *testing.T) {
+	t.Parallel()
+
+	testErr := errors.New("test")
+
+	m := closertest.NewMock()
+	ctx, cancel := m.Context()
+	defer cancel()
+
+	var called bool
+	m.OnClose(func() error {
+		called = true
+		return nil
+	})
+
+	m.SimulateClosed(testErr)
+	r.ErrorIs(t, ctx.Err(), context.Canceled)
+	r.Equal(t, closer.StateClosed, m.State())
+	r.ErrorIs(t, m.CloserError(), testErr)
+	r.ErrorIs(t, m.CloserWait(context.Background()), testErr)
+	r.ErrorIs(t, m.Close(), testErr)
+	r.False(t, called)
+}
+
+func TestMock_ClosingOrder(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m     = closertest.NewMock()
+		child = m.CloserOneWay()
+		order []string
+	)
+	m.OnCloseFinal(func(errs []error) error {
+		r.Len(t, errs, 1)
+		order = append(order, "final")
+		return nil
+	})
+	m.OnClose(func() error {
+		order = append(order, "close")
+		return nil
+	})
+	child.OnClose(func() error {
+		order = append(order, "child")
+		return errors.New("child")
+	})
+	m.OnBeforeChildrenClose(func() error {
+		order = append(order, "beforeChildren")
+		return nil
+	})
+	m.OnClosing(func() error {
+		order = append(order, "closing")
+		return nil
+	})
+	m.OnCloseLast(func() error {
+		order = append(order, "last")
+		return nil
+	})
+
+	r.Len(t, m.ClosePlan(), 6)
+	r.Equal(t, 1, m.NumChildren())
+
+	res := m.CloseTree()
+	r.Equal(t, []string{"closing", "beforeChildren", "child", "close", "final", "last"}, order)
+	r.NoError(t, res.Err)
+	r.Len(t, res.Children, 1)
+	r.Error(t, res.Children[0].Err)
+	r.Error(t, m.CloserError())
+	r.True(t, child.IsClosed())
+}
+
+func TestMock_Phase(t *testing.T) {
+	t.Parallel()
+
+	var (
+		m     = closertest.NewMock()
+		order []string
+	)
+	add := func(register func(...closer.CloseFunc), name string) {
+		register(func() error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	m.SetPhaseOrder("network", "storage")
+	add(m.Phase("storage").OnClose, "storage")
+	add(m.Phase("network").OnClose, "network")
+	add(m.OnClose, "plain")
+
+	r.NoError(t, m.Close())
+	r.Equal(t, []string{"plain", "network", "storage"}, order)
+	r.Equal(t, 2, m.Calls("Phase"))
+}
+
+func TestMock_CloseExcept(t *testing.T) {
+	t.Parallel()
+
+	m := closertest.NewMock()
+	kept := m.CloserTwoWay()
+	closed := m.CloserOneWay()
+
+	r.NoError(t, m.CloseExcept(kept))
+	r.True(t, closed.IsClosed())
+	r.False(t, kept.IsClosing())
+	r.Zero(t, m.NumChildren())
+}
+
+func TestMock_Errors(t *testing.T) {
+	t.Parallel()
+
+	// Panics are recovered like by a real closer.
+	m := closertest.NewMock()
+	m.OnClosing(func() error { panic("closing") })
+	m.OnClose(func() error { panic("close") })
+	m.OnCloseFinal(func([]error) error { panic("final") })
+	err := m.Close()
+	r.ErrorIs(t, err, closer.ErrPanic)
+	r.Contains(t, err.Error(), "closing")
+	r.Contains(t, err.Error(), "close")
+	r.Contains(t, err.Error(), "final")
+
+	// Errors added while closing are kept.
+	testErr := errors.New("test")
+	m = closertest.NewMock()
+	m.SimulateClosing()
+	m.CloseWithErr(testErr)
+	r.ErrorIs(t, m.CloserError(), testErr)
+
+	// A child added to a closing mock stays open like with a real closer.
+	m = closertest.NewMock()
+	m.SimulateClosing()
+	child := m.CloserOneWay()
+	r.False(t, child.IsClosing())
+}
+
+func TestMock_Children(t *testing.T) {
+	t.Parallel()
+
+	m := closertest.NewMock()
+	var added, removed int
+	m.OnChildAdded(func(closer.Closer) { added++ })
+	m.OnChildRemoved(func(closer.Closer) { removed++ })
+
+	child := m.CloserTwoWay()
+	r.Len(t, m.ChildMocks(), 1)
+	r.Equal(t, 1, m.Calls("CloserTwoWay"))
+	r.Equal(t, 1, added)
+
+	// A child closes independently and removes itself from the mock.
+	r.NoError(t, child.Close())
+	r.False(t, m.IsClosing())
+	r.Empty(t, m.ChildMocks())
+	r.Equal(t, 1, removed)
+
+	// The children of the other mock are moved in order.
+	other := closertest.NewMock()
+	a := other.CloserOneWay().(*closertest.Mock)
+	a.SetName("a")
+	b := other.CloserOneWay().(*closertest.Mock)
+	r.NoError(t, m.Adopt(other))
+	r.Equal(t, []*closertest.Mock{a, b}, m.ChildMocks())
+	r.Empty(t, other.ChildMocks())
+	r.Equal(t, 3, added)
+	r.ErrorIs(t, a.Adopt(m), closer.ErrCycle)
+	r.ErrorIs(t, m.Adopt(m), closer.ErrCycle)
+	r.ErrorIs(t, m.Adopt(closer.New()), closer.ErrUnknownCloser)
+
+	// The adopted children are closed with their new parent.
+	r.NoError(t, m.Close())
+	r.True(t, b.IsClosed())
+	r.False(t, other.IsClosing())
+
+	// A simulated close does not remove the child.
+	m = closertest.NewMock()
+	a = m.CloserOneWay().(*closertest.Mock)
+	a.SetName("a")
+	a.SimulateClosed(nil)
+	r.NoError(t, m.WaitChild(context.Background(), "a"))
+	r.ErrorIs(t, m.WaitChild(context.Background(), "unknown"), closer.ErrChildNotFound)
+}
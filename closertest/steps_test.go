@@ -0,0 +1,83 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closertest_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	"github.com/desertbit/closer/v3/closertest"
+	r "github.com/stretchr/testify/require"
+)
+
+const stepPrefix = "github.com/desertbit/closer/v3/closertest_test."
+
+var executed bool
+
+func stopServer() error  { executed = true; return nil }
+func flushCache() error  { executed = true; return nil }
+func closeDB() error     { executed = true; return nil }
+func notifyPeers() error { executed = true; return nil }
+
+func TestSteps(t *testing.T) {
+	executed = false
+	c := closer.New()
+	r.Empty(t, closertest.Steps(c))
+
+	c.OnClosing(notifyPeers)
+	flush := c.OnCloseDep(flushCache)
+	c.OnCloseDep(closeDB, flush)
+	c.OnClose(stopServer)
+	c.CloserOneWay(closer.WithName("worker"))
+
+	r.Equal(t, []string{
+		stepPrefix + "notifyPeers",
+		"worker",
+		stepPrefix + "stopServer",
+		stepPrefix + "flushCache",
+		stepPrefix + "closeDB",
+	}, closertest.Steps(c))
+
+	// The steps must not have been executed.
+	r.False(t, executed)
+	r.False(t, c.IsClosing())
+	r.NoError(t, c.Close())
+	r.True(t, executed)
+}
+
+func TestSteps_Mock(t *testing.T) {
+	m := closertest.NewMock()
+	m.OnClose(closeDB, stopServer)
+	m.OnCloseFinal(func([]error) error { return nil })
+
+	steps := closertest.Steps(m)
+	r.Len(t, steps, 3)
+	r.Equal(t, stepPrefix+"stopServer", steps[0])
+	r.Equal(t, stepPrefix+"closeDB", steps[1])
+	r.Equal(t, 1, m.Calls("ClosePlan"))
+}
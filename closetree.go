@@ -0,0 +1,69 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "time"
+
+// A TreeResult is the result of a single closer of a closed tree, see Closer.CloseTree().
+type TreeResult struct {
+	// ID is the unique ID of the closer.
+	ID uint64
+	// Name is the name of the closer, see WithName().
+	Name string
+	// Err contains the errors of the closer, without the errors of its children.
+	Err error
+	// Duration is the duration of the close, see Closer.CloseDuration().
+	Duration time.Duration
+	// Children contains the results of the children closed by the closer.
+	Children []TreeResult
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseTree() TreeResult {
+	_ = c.Close()
+	return c.treeResult()
+}
+
+// treeResult assembles the result of the closed tree starting at this closer.
+func (c *closer) treeResult() TreeResult {
+	c.mx.Lock()
+	res := TreeResult{
+		ID:       c.id,
+		Name:     c.opts.name,
+		Err:      c.ownErr,
+		Duration: c.closeDuration(),
+	}
+	children := c.closedChildren
+	c.mx.Unlock()
+
+	// A child is closed, before its parent is closed.
+	for _, child := range children {
+		res.Children = append(res.Children, child.treeResult())
+	}
+	return res
+}
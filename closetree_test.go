@@ -0,0 +1,109 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_CloseTree(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errRoot = errors.New("root")
+		errA    = errors.New("a")
+		errAA   = errors.New("aa")
+	)
+
+	c := closer.New(closer.WithName("root"))
+	c.OnClose(func() error { return errRoot })
+	a := c.CloserOneWay(closer.WithName("a"))
+	a.OnClosing(func() error { return errA })
+	aa := a.CloserTwoWay(closer.WithName("aa"))
+	aa.OnClose(func() error { return errAA })
+	_ = c.CloserOneWay(closer.WithName("b"))
+
+	// A child closed before is not part of the result.
+	early := c.CloserOneWay(closer.WithName("early"))
+	r.NoError(t, early.Close())
+
+	res := c.CloseTree()
+	r.Equal(t, "root", res.Name)
+	r.Equal(t, c.ID(), res.ID)
+	r.ErrorIs(t, res.Err, errRoot)
+	r.NotErrorIs(t, res.Err, errA)
+	r.Equal(t, c.CloseDuration(), res.Duration)
+	r.Len(t, res.Children, 2)
+
+	ra := res.Children[0]
+	r.Equal(t, "a", ra.Name)
+	r.ErrorIs(t, ra.Err, errA)
+	r.NotErrorIs(t, ra.Err, errAA)
+	r.Len(t, ra.Children, 1)
+	r.Equal(t, "aa", ra.Children[0].Name)
+	r.ErrorIs(t, ra.Children[0].Err, errAA)
+	r.Empty(t, ra.Children[0].Children)
+
+	rb := res.Children[1]
+	r.Equal(t, "b", rb.Name)
+	r.NoError(t, rb.Err)
+	r.Empty(t, rb.Children)
+
+	// The joined error still contains all errors.
+	err := c.CloserError()
+	r.ErrorIs(t, err, errRoot)
+	r.ErrorIs(t, err, errA)
+	r.ErrorIs(t, err, errAA)
+
+	// The result is stable after the close.
+	r.Equal(t, res, c.CloseTree())
+}
+
+func TestCloser_CloseTreeSetName(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New(closer.WithName("conn"))
+	child := c.CloserOneWay()
+	r.Equal(t, "conn", c.Name())
+
+	// Rename after the handshake revealed the peer.
+	c.SetName("conn-peer-42")
+	child.SetName("stream")
+	r.Equal(t, "conn-peer-42", c.Name())
+	r.Equal(t, "conn-peer-42", c.Stats().Name)
+	r.Equal(t, "stream", c.ClosePlan()[0].Name)
+
+	res := c.CloseTree()
+	r.Equal(t, "conn-peer-42", res.Name)
+	r.Len(t, res.Children, 1)
+	r.Equal(t, "stream", res.Children[0].Name)
+}
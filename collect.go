@@ -0,0 +1,58 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "time"
+
+// A StepResult is the outcome of a single step of a closing order, see Closer.CloseAndCollect().
+type StepResult struct {
+	// Phase is the part of the closing order the step has been executed in.
+	Phase Phase
+	// Name is the function name of a func or the closer name of a child.
+	Name string
+	// Err is the error returned by the step. For a child, it contains
+	// the errors of its whole subtree.
+	Err error
+	// Duration is the time the step took to execute.
+	Duration time.Duration
+}
+
+// Implements the Closer interface.
+func (c *closer) CloseAndCollect() []StepResult {
+	c.mx.Lock()
+	if !c.IsClosing() {
+		c.collectSteps = true
+	}
+	c.mx.Unlock()
+
+	_ = c.Close()
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return append([]StepResult(nil), c.stepResults...)
+}
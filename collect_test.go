@@ -0,0 +1,97 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_CloseAndCollect(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		errA    = errors.New("a")
+		errB    = errors.New("b")
+		errDone = errors.New("done")
+	)
+	c.OnClosing(func() error { return nil })
+	c.OnClose(func() error { return errA })
+	c.OnClose(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	child := c.CloserOneWay(closer.WithName("child"))
+	child.OnClose(func() error { return errB })
+	c.OnCloseFinal(func(errs []error) error { return errDone })
+
+	plan := c.ClosePlan()
+	results := c.CloseAndCollect()
+	r.Len(t, results, len(plan))
+	for i, s := range plan {
+		r.Equal(t, s.Phase, results[i].Phase)
+		r.Equal(t, s.Name, results[i].Name)
+	}
+
+	r.NoError(t, results[0].Err)
+	r.Equal(t, closer.PhaseChildren, results[1].Phase)
+	r.Equal(t, "child", results[1].Name)
+	r.ErrorIs(t, results[1].Err, errB)
+	r.NoError(t, results[2].Err)
+	r.GreaterOrEqual(t, results[2].Duration, 10*time.Millisecond)
+	r.ErrorIs(t, results[3].Err, errA)
+	r.ErrorIs(t, results[4].Err, errDone)
+
+	// The results are kept, once closed.
+	r.Equal(t, results, c.CloseAndCollect())
+
+	// A closer, which has been closed by Close(), does not collect any results.
+	c = closer.New()
+	c.OnClose(func() error { return nil })
+	r.NoError(t, c.Close())
+	r.Empty(t, c.CloseAndCollect())
+}
+
+func TestCloser_CloseAndCollectFailFast(t *testing.T) {
+	t.Parallel()
+
+	errA := errors.New("a")
+	c := closer.New(closer.WithFailFast())
+	c.OnClose(func() error { return nil })
+	c.OnClose(func() error { return errA })
+
+	// The skipped step is not part of the results.
+	results := c.CloseAndCollect()
+	r.Len(t, results, 1)
+	r.ErrorIs(t, results[0].Err, errA)
+}
@@ -0,0 +1,73 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestDrainOnClose(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		jobs    = make(chan int, 10)
+		drained []int
+	)
+	for i := 0; i < 5; i++ {
+		jobs <- i
+	}
+	closer.DrainOnClose(c, jobs, func(job int) {
+		drained = append(drained, job)
+	})
+	c.OnClose(func() error {
+		r.Equal(t, []int{0, 1, 2, 3, 4}, drained)
+		return nil
+	})
+
+	r.NoError(t, c.Close())
+	r.Len(t, drained, 5)
+	r.Empty(t, jobs)
+
+	// A closed channel stops the drain.
+	c = closer.New()
+	jobs = make(chan int, 10)
+	jobs <- 5
+	close(jobs)
+	drained = nil
+	closer.DrainOnClose(c, jobs, func(job int) {
+		drained = append(drained, job)
+	})
+	r.NoError(t, c.BeginClosing())
+	r.Equal(t, []int{5}, drained)
+	r.NoError(t, c.Close())
+	r.Equal(t, []int{5}, drained)
+}
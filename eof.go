@@ -0,0 +1,71 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"io"
+	"time"
+)
+
+// A readDeadliner is a reader with a read deadline, like a net.Conn.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// CloseOnEOF closes the closer, once reading from the given reader fails,
+// e.g. because the controlling pipe or socket of a daemon has been closed
+// by the other side. The closer is closed with the read error, including io.EOF.
+// All data read is discarded, so the reader should be dedicated to this purpose.
+//
+// The reading goroutine terminates, if the closer closes first and the reader
+// has a read deadline, like a net.Conn or an *os.File pipe. Its read deadline
+// is then set to the current time to abort the pending read. Otherwise, the
+// goroutine only terminates once the reader returns an error.
+func CloseOnEOF(c Closer, r io.Reader) {
+	if rd, ok := r.(readDeadliner); ok {
+		go func() {
+			<-c.ClosingChan()
+			_ = rd.SetReadDeadline(time.Now())
+		}()
+	}
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			_, err := r.Read(buf)
+			if err == nil {
+				continue
+			}
+			// Do not report the aborted read, if the closer closed first.
+			if !c.IsClosing() {
+				c.CloseWithErr(err)
+			}
+			return
+		}
+	}()
+}
@@ -0,0 +1,79 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloseOnEOF(t *testing.T) {
+	t.Parallel()
+
+	pr, pw := io.Pipe()
+	c := closer.New()
+	closer.CloseOnEOF(c, pr)
+
+	// Data does not close the closer.
+	_, err := pw.Write([]byte("ping"))
+	r.NoError(t, err)
+	r.False(t, c.IsClosing())
+
+	r.NoError(t, pw.Close())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), io.EOF)
+}
+
+func TestCloseOnEOF_Conn(t *testing.T) {
+	t.Parallel()
+
+	c1, c2 := net.Pipe()
+	defer c2.Close()
+
+	c := closer.New()
+	closer.CloseOnEOF(c, c1)
+
+	// Closing the closer first aborts the pending read without an error.
+	r.NoError(t, c.Close())
+	time.Sleep(50 * time.Millisecond)
+	r.NoError(t, c.CloserError())
+
+	// The read has been aborted by the read deadline.
+	_, err := c1.Read(make([]byte, 1))
+	r.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
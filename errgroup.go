@@ -0,0 +1,111 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// An ErrGroup runs goroutines under the wait group of a closer and
+// collects their errors. It mirrors golang.org/x/sync/errgroup.Group.
+//
+// The first goroutine returning an error closes the closer with this error,
+// which cancels the group's context. Likewise, closing the closer cancels
+// the group's context.
+type ErrGroup struct {
+	c      Closer
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mx  sync.Mutex
+	err error
+}
+
+// NewErrGroup returns a new ErrGroup for the closer and its context,
+// which is derived from the closer's Context().
+func NewErrGroup(c Closer) (*ErrGroup, context.Context) {
+	ctx, cancel := c.Context()
+	return &ErrGroup{
+		c:      c,
+		cancel: cancel,
+	}, ctx
+}
+
+// Go calls the given function in a new goroutine.
+// The closer waits for the goroutine during Close().
+// If the closer is already closing, f is not called.
+// A panic in f is recovered and handled as error wrapping ErrPanic.
+func (g *ErrGroup) Go(f func() error) {
+	if g.c.IsClosing() {
+		return
+	}
+
+	g.wg.Add(1)
+	// The closer might start closing in the meantime.
+	// Do not log a warning, because this case is handled below.
+	if c, ok := g.c.(*closer); ok {
+		c.closerAddWait(1, false)
+	} else {
+		g.c.CloserAddWait(1)
+	}
+	go func() {
+		// CloserAddWait will also add to a closed closer. Ensure we are not in a closing state.
+		if g.c.IsClosing() {
+			g.c.CloserDone()
+			g.wg.Done()
+			return
+		}
+
+		err := callRoutine(f)
+		if err == nil {
+			g.c.CloserDone()
+			g.wg.Done()
+			return
+		}
+
+		g.mx.Lock()
+		g.err = errors.Join(g.err, err)
+		g.mx.Unlock()
+		g.wg.Done()
+
+		g.c.CloseWithErrAndDone(err)
+	}()
+}
+
+// Wait blocks until all goroutines started with Go() have returned.
+// It returns the joined errors of all goroutines and cancels the group's context.
+func (g *ErrGroup) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.err
+}
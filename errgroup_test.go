@@ -0,0 +1,145 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func ExampleNewErrGroup() {
+	c := closer.New()
+	g, ctx := closer.NewErrGroup(c)
+
+	for _, url := range []string{"a", "b", "c"} {
+		url := url
+		g.Go(func() error {
+			if url == "b" {
+				return fmt.Errorf("fetch %s: failed", url)
+			}
+			// Block until another routine fails.
+			<-ctx.Done()
+			return nil
+		})
+	}
+
+	fmt.Println(g.Wait())
+	<-c.ClosedChan()
+	fmt.Println(c.CloserError())
+	// Output:
+	// fetch b: failed
+	// fetch b: failed
+}
+
+func TestErrGroup(t *testing.T) {
+	t.Parallel()
+
+	// Closing the closer cancels the group.
+	c := closer.New()
+	g, ctx := closer.NewErrGroup(c)
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			<-ctx.Done()
+			return nil
+		})
+	}
+
+	// The closer must wait for the routines.
+	r.NoError(t, c.Close())
+	r.NoError(t, g.Wait())
+	r.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	// No routine is started for a closed closer.
+	g.Go(func() error {
+		return errors.New("must not run")
+	})
+	r.NoError(t, g.Wait())
+
+	// Errors are joined.
+	// Both routines must be running, before the first error closes the closer.
+	var (
+		errA    = errors.New("a")
+		errB    = errors.New("b")
+		started sync.WaitGroup
+	)
+	started.Add(2)
+	c = closer.New()
+	g, _ = closer.NewErrGroup(c)
+	for _, err := range []error{errA, errB} {
+		err := err
+		g.Go(func() error {
+			started.Done()
+			started.Wait()
+			return err
+		})
+	}
+
+	err := g.Wait()
+	r.ErrorIs(t, err, errA)
+	r.ErrorIs(t, err, errB)
+
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), errA)
+	r.ErrorIs(t, c.CloserError(), errB)
+}
+
+func TestErrGroup_GoWhileClosing(t *testing.T) {
+	// Not parallel, because the log output is redirected.
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for i := 0; i < 100; i++ {
+		c := closer.New()
+		g, _ := closer.NewErrGroup(c)
+		go c.Close_()
+		for j := 0; j < 10; j++ {
+			g.Go(func() error { return nil })
+		}
+		r.NoError(t, g.Wait())
+		<-c.ClosedChan()
+	}
+
+	// Racing with the close must not log warnings about adding to a closing closer.
+	r.Empty(t, buf.String())
+}
@@ -0,0 +1,58 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "expvar"
+
+type expvarSnapshot struct {
+	ID                   uint64  `json:"id"`
+	Name                 string  `json:"name"`
+	State                string  `json:"state"`
+	Children             int     `json:"children"`
+	PendingWaits         int     `json:"pendingWaits"`
+	CloseDurationSeconds float64 `json:"closeDurationSeconds"`
+}
+
+// PublishExpvar publishes the state of the closer as expvar variable
+// with the given name. The variable is a JSON object, containing the
+// closer's ID, name, state, number of children, pending waits and close duration.
+// It is updated on each read, e.g. by scraping /debug/vars.
+// Like expvar.Publish, this panics if the name is already registered.
+func PublishExpvar(name string, c Closer) {
+	expvar.Publish(name, expvar.Func(func() any {
+		s := c.Stats()
+		return expvarSnapshot{
+			ID:                   c.ID(),
+			Name:                 s.Name,
+			State:                s.State.String(),
+			Children:             s.NumChildren,
+			PendingWaits:         s.PendingWaits,
+			CloseDurationSeconds: s.CloseDuration.Seconds(),
+		}
+	}))
+}
@@ -0,0 +1,82 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	// The name must be unique, because expvar does not allow to unpublish it.
+	c := closer.New(closer.WithName("root"))
+	name := fmt.Sprintf("closer_test_root_%d", c.ID())
+	closer.PublishExpvar(name, c)
+
+	read := func() (v map[string]any) {
+		r.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &v))
+		return
+	}
+
+	v := read()
+	r.Equal(t, float64(c.ID()), v["id"])
+	r.Equal(t, "root", v["name"])
+	r.Equal(t, "open", v["state"])
+	r.Equal(t, float64(0), v["children"])
+	r.Equal(t, float64(0), v["pendingWaits"])
+	r.Equal(t, float64(0), v["closeDurationSeconds"])
+
+	_ = c.CloserOneWay()
+	_ = c.CloserOneWay()
+	c.CloserAddWait(1)
+
+	v = read()
+	r.Equal(t, float64(2), v["children"])
+	r.Equal(t, float64(1), v["pendingWaits"])
+
+	c.OnClose(func() error {
+		v := read()
+		r.Equal(t, "closing", v["state"])
+		r.Equal(t, float64(0), v["pendingWaits"])
+		return nil
+	})
+	go c.CloseAndDone_()
+	<-c.ClosedChan()
+
+	v = read()
+	r.Equal(t, "closed", v["state"])
+	r.Equal(t, float64(0), v["children"])
+	r.Greater(t, v["closeDurationSeconds"], float64(0))
+}
@@ -0,0 +1,73 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"log"
+	"runtime"
+)
+
+// A leakDetector is owned by a closer and logs a warning, if it is garbage
+// collected while the closer is not closed.
+// The finalizer can not be set on the closer itself, because the closer
+// references itself and finalizers are not guaranteed to run for cycles.
+// The leakDetector must therefore never reference its closer.
+type leakDetector struct {
+	closingChan <-chan struct{}
+	logger      Logger
+	trace       string
+}
+
+func newLeakDetector(c *closer, trace string) *leakDetector {
+	d := &leakDetector{
+		closingChan: c.closingChan,
+		logger:      c.opts.logger,
+		trace:       trace,
+	}
+	runtime.SetFinalizer(d, (*leakDetector).finalize)
+	return d
+}
+
+func (d *leakDetector) finalize() {
+	select {
+	case <-d.closingChan:
+		return
+	default:
+	}
+
+	msg := "Warning: closer garbage collected without being closed"
+	// Append the creation stacktrace if build with debugging mode.
+	if debugEnabled {
+		msg += ":\n" + d.trace
+	}
+	if d.logger != nil {
+		d.logger.Println(msg)
+	} else {
+		log.Println(msg)
+	}
+}
@@ -0,0 +1,143 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// An Origin describes what initiated the close of a closer, see Closer.InitiatedBy().
+type Origin int
+
+const (
+	// OriginNone indicates that the closer is not yet closing.
+	OriginNone Origin = iota
+	// OriginDirect indicates a direct close of the closer, e.g. by Close().
+	OriginDirect
+	// OriginParent indicates a close by the parent, see Closer.ClosedByParent().
+	OriginParent
+	// OriginChild indicates a close by a two-way child, see Closer.CloserTwoWay().
+	OriginChild
+	// OriginLink indicates a close by a linked closer, see Closer.Link().
+	OriginLink
+)
+
+// String implements the fmt.Stringer interface.
+func (o Origin) String() string {
+	switch o {
+	case OriginNone:
+		return "none"
+	case OriginDirect:
+		return "direct"
+	case OriginParent:
+		return "parent"
+	case OriginChild:
+		return "child"
+	case OriginLink:
+		return "link"
+	default:
+		return "unknown"
+	}
+}
+
+// An initiator describes what initiated the close of a closer.
+type initiator struct {
+	origin Origin
+	// The closer, which initiated the close. May be nil.
+	by Closer
+	// The reason recorded for the close, if no other reason has been recorded.
+	reason string
+}
+
+// byParent returns the initiator of a close by the parent p.
+func byParent(p *closer) initiator {
+	return initiator{origin: OriginParent, by: p}
+}
+
+// byLink returns the initiator of a close by the linked closer.
+func byLink(by Closer) initiator {
+	reason := "link"
+	if name := by.Name(); name != "" {
+		reason += ": " + name
+	}
+	return initiator{origin: OriginLink, by: by, reason: reason}
+}
+
+// Implements the Closer interface.
+func (c *closer) InitiatedBy() (Origin, Closer) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.initiator.origin, c.initiator.by
+}
+
+// Implements the Closer interface.
+func (c *closer) Link(other Closer) {
+	if other == nil || other == Closer(c) {
+		return
+	}
+
+	c.addLink(other)
+	if o, ok := other.(*closer); ok {
+		o.addLink(c)
+		return
+	}
+
+	// Other implementations are linked by their closing funcs.
+	other.OnClosing(func() error {
+		go c.closeBy(byLink(other))
+		return nil
+	})
+}
+
+// addLink adds the other closer to the links of this closer.
+// If this closer is already closing, the other closer is closed immediately.
+func (c *closer) addLink(other Closer) {
+	c.mx.Lock()
+	if !c.IsClosing() {
+		c.links = append(c.links, other)
+		c.mx.Unlock()
+		return
+	}
+	c.mx.Unlock()
+
+	c.closeLinks([]Closer{other})
+}
+
+// closeLinks closes the given linked closers without waiting for them.
+// The closer's mutex must not be locked.
+func (c *closer) closeLinks(links []Closer) {
+	if len(links) == 0 {
+		return
+	}
+
+	init := byLink(c)
+	for _, l := range links {
+		if lc, ok := l.(*closer); ok {
+			go lc.closeBy(init)
+		} else {
+			go l.Close_()
+		}
+	}
+}
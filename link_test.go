@@ -0,0 +1,161 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func waitClosed(t *testing.T, c closer.Closer) {
+	t.Helper()
+
+	select {
+	case <-c.ClosedChan():
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestCloser_Link(t *testing.T) {
+	t.Parallel()
+
+	a := closer.New(closer.WithName("a"))
+	b := closer.New(closer.WithName("b"))
+	a.Link(b)
+
+	origin, by := b.InitiatedBy()
+	r.Equal(t, closer.OriginNone, origin)
+	r.Nil(t, by)
+
+	// Closing one closer closes the linked closer.
+	r.NoError(t, a.Close())
+	waitClosed(t, b)
+
+	origin, by = a.InitiatedBy()
+	r.Equal(t, closer.OriginDirect, origin)
+	r.Nil(t, by)
+	r.Empty(t, a.CloseReasonText())
+
+	origin, by = b.InitiatedBy()
+	r.Equal(t, closer.OriginLink, origin)
+	r.Equal(t, a, by)
+	r.Equal(t, "link: a", b.CloseReasonText())
+
+	// Linking a closing closer closes the other closer immediately.
+	c := closer.New()
+	c.Link(a)
+	waitClosed(t, c)
+	origin, by = c.InitiatedBy()
+	r.Equal(t, closer.OriginLink, origin)
+	r.Equal(t, a, by)
+
+	// The link works in both directions and without a name.
+	d, e := closer.New(), closer.New()
+	d.Link(e)
+	r.NoError(t, e.Close())
+	waitClosed(t, d)
+	origin, by = d.InitiatedBy()
+	r.Equal(t, closer.OriginLink, origin)
+	r.Equal(t, e, by)
+	r.Equal(t, "link", d.CloseReasonText())
+}
+
+func TestCloser_InitiatedBy(t *testing.T) {
+	t.Parallel()
+
+	// Children closed by their parent.
+	p := closer.New()
+	c := p.CloserOneWay()
+	r.NoError(t, p.Close())
+	origin, by := c.InitiatedBy()
+	r.Equal(t, closer.OriginParent, origin)
+	r.Equal(t, p, by)
+
+	// A parent closed by its two-way child.
+	p = closer.New()
+	c = p.CloserTwoWay()
+	r.NoError(t, c.Close())
+	waitClosed(t, p)
+	origin, by = p.InitiatedBy()
+	r.Equal(t, closer.OriginChild, origin)
+	r.Equal(t, c, by)
+	r.Empty(t, p.CloseReasonText())
+}
+
+func TestCloser_PropagateOnce(t *testing.T) {
+	t.Parallel()
+
+	const n = 8
+
+	for i := 0; i < 100; i++ {
+		var (
+			p        = closer.New()
+			children = make([]closer.Closer, n)
+			calls    atomic.Int64
+			start    = make(chan struct{})
+			wg       sync.WaitGroup
+		)
+		p.OnClose(func() error {
+			calls.Add(1)
+			return nil
+		})
+		for j := range children {
+			children[j] = p.CloserTwoWay()
+		}
+		// Link the children in a ring, so that each close reaches the parent
+		// directly and across the links.
+		for j := range children {
+			children[j].Link(children[(j+1)%n])
+		}
+
+		for _, child := range children {
+			wg.Add(2)
+			go func(child closer.Closer) {
+				defer wg.Done()
+				<-start
+				child.Close_()
+			}(child)
+			go func(child closer.Closer) {
+				defer wg.Done()
+				<-start
+				child.CloseParentOnly(nil)
+			}(child)
+		}
+		close(start)
+		wg.Wait()
+
+		waitClosed(t, p)
+		r.Equal(t, int64(1), calls.Load())
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "time"
+
+// A Logger logs the warnings of a closer. A *log.Logger implements it.
+type Logger interface {
+	Println(v ...any)
+}
+
+// An Option configures a closer.
+type Option func(o *options)
+
+// A DoneUnderflowPolicy defines how a closer handles a CloserDone call,
+// which would decrement its wait group below zero.
+type DoneUnderflowPolicy int
+
+const (
+	// DoneUnderflowPanic panics. This is the default.
+	DoneUnderflowPanic DoneUnderflowPolicy = iota
+	// DoneUnderflowIgnore ignores the call.
+	DoneUnderflowIgnore
+	// DoneUnderflowLog logs a warning and ignores the call.
+	DoneUnderflowLog
+)
+
+// A MaxCloseFuncsPolicy defines how a closer handles the registration of
+// an OnClose func, which exceeds the limit set with WithMaxCloseFuncs().
+type MaxCloseFuncsPolicy int
+
+const (
+	// MaxCloseFuncsPanic panics and does not register the func.
+	MaxCloseFuncsPanic MaxCloseFuncsPolicy = iota
+	// MaxCloseFuncsLog logs a warning once and registers the func.
+	MaxCloseFuncsLog
+)
+
+type options struct {
+	name                 string
+	failFast             bool
+	doneUnderflowPolicy  DoneUnderflowPolicy
+	deadline             time.Time
+	closeTimeout         time.Duration
+	concurrentChildClose int
+	maxCloseFuncs        int
+	maxCloseFuncsPolicy  MaxCloseFuncsPolicy
+	logger               Logger
+	labels               map[string]string
+	errorHook            func(c Closer, err error)
+	tracer               CloseTracer
+	leakDetection        bool
+	interrupt            bool
+	resourceAccounting   bool
+}
+
+// WithName sets the name of the closer.
+// The name is used to describe the closer, e.g. in its ClosePlan().
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithFailFast stops the execution of the OnClosing and OnClose funcs
+// as soon as the first error occurs during Close(). Only this first
+// error is returned. Funcs that would run afterwards, which are the
+// earlier registered funcs due to the LIFO order, are skipped.
+// Children are still closed and the wait group is still awaited,
+// but any of their errors after the first error are dropped.
+// The OnCloseFinal funcs are an exception: they are always executed to report
+// the first error, but their own errors are dropped after the first error as well.
+func WithFailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+// WithDoneUnderflowPolicy sets how CloserDone calls are handled,
+// which would decrement the wait group below zero.
+// Defaults to DoneUnderflowPanic.
+func WithDoneUnderflowPolicy(p DoneUnderflowPolicy) Option {
+	return func(o *options) {
+		o.doneUnderflowPolicy = p
+	}
+}
+
+// WithDeadline closes the closer with ErrDeadlineExceeded, once the given point
+// in time has passed. See Closer.SetDeadline() to change the deadline later.
+func WithDeadline(t time.Time) Option {
+	return func(o *options) {
+		o.deadline = t
+	}
+}
+
+// WithCloseTimeout bounds the closing order of Close() by the given timeout,
+// see Closer.SetCloseTimeout() to change the timeout later.
+func WithCloseTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.closeTimeout = d
+	}
+}
+
+// WithConcurrentChildClose lets the closer close its children across up to n
+// goroutines, instead of closing one child after another. This reduces the
+// latency of closing trees with many children, whose closing orders block.
+// The closer still waits for all children to close, before it continues with
+// its closing order. The errors of the children are joined in the order of the children.
+// A value of one or less closes the children serially, which is the default.
+// The option is not inherited by the children.
+func WithConcurrentChildClose(n int) Option {
+	return func(o *options) {
+		o.concurrentChildClose = n
+	}
+}
+
+// WithMaxCloseFuncs limits the number of OnClose funcs of the closer to n,
+// which flags a likely leak, e.g. funcs registered per request on a long-lived closer.
+// The policy defines how a registration beyond the limit is handled.
+// A limit of zero or less disables the limit, which is the default.
+// The option is not inherited by the children.
+func WithMaxCloseFuncs(n int, p MaxCloseFuncsPolicy) Option {
+	return func(o *options) {
+		o.maxCloseFuncs = n
+		o.maxCloseFuncsPolicy = p
+	}
+}
+
+// WithLogger sets the logger for the warnings of the closer.
+// Defaults to the standard logger of the log package.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithLabels sets the labels of the closer, see Closer.Labels().
+// The labels are copied.
+func WithLabels(labels map[string]string) Option {
+	return func(o *options) {
+		o.labels = copyLabels(labels)
+	}
+}
+
+// WithErrorHook sets a func, which is called once the closer
+// is closed with an error. It receives the closer and its close error.
+func WithErrorHook(f func(c Closer, err error)) Option {
+	return func(o *options) {
+		o.errorHook = f
+	}
+}
+
+// WithCloseTracer sets a tracer, which traces the closing order of the closer,
+// see CloseTracer. The tracer is inherited by the children.
+func WithCloseTracer(t CloseTracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithLeakDetection logs a warning with the closer's logger, if the closer is
+// garbage collected without being closed. Debug builds include the stacktrace
+// of its creation. This is intended to find forgotten closers during development.
+// The closer is not kept alive by the leak detection.
+func WithLeakDetection() Option {
+	return func(o *options) {
+		o.leakDetection = true
+	}
+}
+
+// WithInterrupt closes the closer on the first SIGINT or SIGTERM signal with the
+// close reason "signal: <name>", see Closer.CloseReasonText(). This is intended
+// for the root closer of an application. The signal handler is uninstalled,
+// once the closer is closing, so that a further signal terminates the process.
+// The option is not inherited by the children.
+func WithInterrupt() Option {
+	return func(o *options) {
+		o.interrupt = true
+	}
+}
+
+// WithResourceAccounting records the io.Closers registered with OnCloseCloser()
+// by their type and counts, which of them closed cleanly and which failed.
+// See Closer.ResourceReport(). The option is not inherited by the children.
+func WithResourceAccounting() Option {
+	return func(o *options) {
+		o.resourceAccounting = true
+	}
+}
+
+// copyLabels returns a copy of the labels or nil, if there are none.
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	cp := make(map[string]string, len(labels))
+	for k, v := range labels {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,372 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestWithFailFast(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errFirst  = errors.New("first")
+		errSecond = errors.New("second")
+		executed  []int
+	)
+
+	c := closer.New(closer.WithFailFast())
+	c.OnClose(func() error {
+		executed = append(executed, 0)
+		return errSecond
+	})
+	c.OnClose(func() error {
+		executed = append(executed, 1)
+		return errFirst
+	})
+	c.OnClose(func() error {
+		executed = append(executed, 2)
+		return nil
+	})
+
+	err := c.Close()
+	r.ErrorIs(t, err, errFirst)
+	r.NotErrorIs(t, err, errSecond)
+	r.Equal(t, []int{2, 1}, executed)
+	r.True(t, c.IsClosed())
+
+	// A failing closing func also skips all close funcs.
+	c = closer.New(closer.WithFailFast())
+	c.OnClose(func() error {
+		executed = append(executed, 3)
+		return nil
+	})
+	c.OnClosing(func() error {
+		return errFirst
+	})
+
+	r.ErrorIs(t, c.Close(), errFirst)
+	r.Equal(t, []int{2, 1}, executed)
+	r.True(t, c.IsClosed())
+
+	// The final funcs still receive the first error, but their errors are dropped.
+	var finalErrs []error
+	c = closer.New(closer.WithFailFast())
+	c.OnCloseFinal(func(errs []error) error {
+		finalErrs = errs
+		return errSecond
+	})
+	c.OnClose(func() error {
+		return errFirst
+	})
+
+	err = c.Close()
+	r.EqualError(t, err, "first")
+	r.Equal(t, []error{errFirst}, finalErrs)
+
+	// Without a previous error, the error of a final func is the first error.
+	c = closer.New(closer.WithFailFast())
+	c.OnCloseFinal(func([]error) error { return errSecond })
+	c.OnCloseFinal(func([]error) error { return errFirst })
+	r.EqualError(t, c.Close(), "first")
+}
+
+func TestWithDoneUnderflowPolicy(t *testing.T) {
+	// Not parallel, because the log output is redirected.
+
+	// Panic is the default.
+	for _, c := range []closer.Closer{
+		closer.New(),
+		closer.New(closer.WithDoneUnderflowPolicy(closer.DoneUnderflowPanic)),
+	} {
+		r.Panics(t, c.CloserDone)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, p := range []closer.DoneUnderflowPolicy{closer.DoneUnderflowIgnore, closer.DoneUnderflowLog} {
+		buf.Reset()
+
+		c := closer.New(closer.WithDoneUnderflowPolicy(p))
+		r.NotPanics(t, c.CloserDone)
+
+		// The spurious done must not affect the wait group.
+		c.CloserAddWait(1)
+		go c.Close_()
+		select {
+		case <-c.ClosedChan():
+			t.Fatal("closed without waiting")
+		case <-time.After(50 * time.Millisecond):
+		}
+		c.CloserDone()
+		<-c.ClosedChan()
+
+		if p == closer.DoneUnderflowLog {
+			r.Contains(t, buf.String(), "CloserDone called with zero wait counter")
+		} else {
+			r.Empty(t, buf.String())
+		}
+	}
+}
+
+func TestWithMaxCloseFuncs(t *testing.T) {
+	t.Parallel()
+
+	noop := func() error { return nil }
+
+	// Panic at the limit.
+	c := closer.New(closer.WithMaxCloseFuncs(2, closer.MaxCloseFuncsPanic))
+	c.OnClose(noop)
+	c.OnCloseDep(noop)
+	r.Panics(t, func() { c.OnClose(noop) })
+	r.Panics(t, func() { c.OnCloseDep(noop) })
+	r.Equal(t, 2, c.Stats().NumCloseFuncs)
+	r.NoError(t, c.Close())
+
+	// Log once at the limit.
+	var buf bytes.Buffer
+	c = closer.New(
+		closer.WithMaxCloseFuncs(1, closer.MaxCloseFuncsLog),
+		closer.WithLogger(log.New(&buf, "", 0)),
+	)
+	c.OnClose(noop)
+	r.Empty(t, buf.String())
+	c.OnClose(noop, noop)
+	c.OnClose(noop)
+	r.Equal(t, 1, strings.Count(buf.String(), "closer exceeds the maximum of 1 close funcs"))
+	r.Equal(t, 4, c.Stats().NumCloseFuncs)
+	r.NoError(t, c.Close())
+
+	// No limit for the children.
+	child := closer.New(closer.WithMaxCloseFuncs(1, closer.MaxCloseFuncsPanic)).CloserOneWay()
+	r.NotPanics(t, func() { child.OnClose(noop, noop) })
+}
+
+func TestOptionsInherited(t *testing.T) {
+	t.Parallel()
+
+	var (
+		buf     bytes.Buffer
+		hookErr = make(chan error, 1)
+		labels  = map[string]string{"service": "api"}
+	)
+	c := closer.New(
+		closer.WithName("parent"),
+		closer.WithDoneUnderflowPolicy(closer.DoneUnderflowLog),
+		closer.WithFailFast(),
+		closer.WithLogger(log.New(&buf, "", 0)),
+		closer.WithLabels(labels),
+		closer.WithErrorHook(func(c closer.Closer, err error) {
+			if c.Name() == "" {
+				hookErr <- err
+			}
+		}),
+	)
+	r.Equal(t, labels, c.Labels())
+
+	// The child logs through the parent's logger.
+	child := c.CloserOneWay()
+	r.Empty(t, child.Name())
+	r.NotPanics(t, child.CloserDone)
+	r.True(t, strings.HasPrefix(buf.String(), "Warning: CloserDone called with zero wait counter"))
+
+	// The labels are copied at creation time.
+	labels["service"] = "changed"
+	r.Equal(t, map[string]string{"service": "api"}, child.Labels())
+	l := child.Labels()
+	l["service"] = "changed"
+	r.Equal(t, map[string]string{"service": "api"}, child.Labels())
+
+	// The fail fast policy and the error hook are inherited.
+	grandChild := child.CloserTwoWay()
+	grandChild.OnClose(func() error { return errors.New("second") }, func() error { return errors.New("first") })
+	r.EqualError(t, grandChild.Close(), "first")
+	r.EqualError(t, <-hookErr, "first")
+
+	// The inherited options can be overridden.
+	child = c.CloserTwoWay(
+		closer.WithDoneUnderflowPolicy(closer.DoneUnderflowPanic),
+		closer.WithLabels(map[string]string{"service": "worker"}),
+	)
+	r.Panics(t, child.CloserDone)
+	r.Equal(t, map[string]string{"service": "worker"}, child.Labels())
+}
+
+func TestWithCloseTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New(closer.WithCloseTimeout(50 * time.Millisecond))
+	c.CloserAddWait(1)
+	start := time.Now()
+	r.ErrorIs(t, c.Close(), context.DeadlineExceeded)
+	r.Less(t, time.Since(start), 3*time.Second)
+
+	// The timeout is not inherited.
+	p := closer.New(closer.WithCloseTimeout(time.Millisecond))
+	child := p.CloserOneWay()
+	child.CloserAddWait(1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		child.CloserDone()
+	}()
+	r.NoError(t, child.Close())
+	r.NoError(t, p.Close())
+}
+
+func TestWithConcurrentChildClose(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errChild = errors.New("child")
+		running  atomic.Int32
+		maxRun   atomic.Int32
+		c        = closer.New(closer.WithConcurrentChildClose(3))
+	)
+	for i := 0; i < 9; i++ {
+		child := c.CloserOneWay()
+		child.OnClose(func() error {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRun.Load()
+				if n <= m || maxRun.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return errChild
+		})
+	}
+
+	// The own close funcs run after all children closed.
+	c.OnClose(func() error {
+		r.Zero(t, running.Load())
+		return nil
+	})
+
+	err := c.Close()
+	r.ErrorIs(t, err, errChild)
+	r.Equal(t, int32(3), maxRun.Load())
+	done, total := c.CloseProgress()
+	r.Equal(t, total, done)
+}
+
+func TestWithDeadline(t *testing.T) {
+	t.Parallel()
+
+	// The deadline closes the closer.
+	c := closer.New(closer.WithDeadline(time.Now().Add(50 * time.Millisecond)))
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), closer.ErrDeadlineExceeded)
+	r.ErrorIs(t, c.SetDeadline(time.Now()), closer.ErrClosing)
+
+	// An earlier close stops the deadline.
+	c = closer.New(closer.WithDeadline(time.Now().Add(50 * time.Millisecond)))
+	r.NoError(t, c.Close())
+	time.Sleep(100 * time.Millisecond)
+	r.NoError(t, c.CloserError())
+
+	// The deadline can be extended.
+	c = closer.New(closer.WithDeadline(time.Now().Add(50 * time.Millisecond)))
+	r.NoError(t, c.SetDeadline(time.Now().Add(200*time.Millisecond)))
+	time.Sleep(100 * time.Millisecond)
+	r.False(t, c.IsClosing())
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), closer.ErrDeadlineExceeded)
+
+	// The deadline can be shortened.
+	c = closer.New(closer.WithDeadline(time.Now().Add(time.Hour)))
+	r.NoError(t, c.SetDeadline(time.Now().Add(50*time.Millisecond)))
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+
+	// The deadline can be removed.
+	c = closer.New(closer.WithDeadline(time.Now().Add(50 * time.Millisecond)))
+	r.NoError(t, c.SetDeadline(time.Time{}))
+	time.Sleep(100 * time.Millisecond)
+	r.False(t, c.IsClosing())
+}
+
+// A chanLogger sends each logged line to the channel.
+type chanLogger chan string
+
+func (l chanLogger) Println(v ...any) {
+	select {
+	case l <- fmt.Sprintln(v...):
+	default:
+	}
+}
+
+func TestWithLeakDetection(t *testing.T) {
+	t.Parallel()
+
+	logs := make(chanLogger, 1)
+	func() {
+		c := closer.New(closer.WithLeakDetection(), closer.WithLogger(logs))
+		c.OnClose(func() error { return nil })
+	}()
+
+	timeout := time.After(3 * time.Second)
+	for {
+		runtime.GC()
+		select {
+		case msg := <-logs:
+			r.Contains(t, msg, "closer garbage collected without being closed")
+			return
+		case <-timeout:
+			t.Fatal("leak not detected")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// Implements the Closer interface.
+func (c *closer) Pause() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.paused || c.IsClosing() {
+		return
+	}
+	c.paused = true
+	if c.pausedChan != nil {
+		close(c.pausedChan)
+	}
+	// A new open chan is created on demand.
+	c.resumedChan = nil
+}
+
+// Implements the Closer interface.
+func (c *closer) Resume() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if !c.paused {
+		return
+	}
+	c.paused = false
+	if c.resumedChan != nil {
+		close(c.resumedChan)
+	}
+	// A new open chan is created on demand.
+	c.pausedChan = nil
+}
+
+// Implements the Closer interface.
+func (c *closer) PausedChan() <-chan struct{} {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// The chans are only created on demand, because most closers are never paused.
+	if c.pausedChan == nil {
+		c.pausedChan = make(chan struct{})
+		if c.paused {
+			close(c.pausedChan)
+		}
+	}
+	return c.pausedChan
+}
+
+// Implements the Closer interface.
+func (c *closer) ResumedChan() <-chan struct{} {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.resumedChan == nil {
+		c.resumedChan = make(chan struct{})
+		if !c.paused {
+			close(c.resumedChan)
+		}
+	}
+	return c.resumedChan
+}
@@ -0,0 +1,101 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestCloser_PauseResume(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	paused := c.PausedChan()
+	r.False(t, isClosed(paused))
+	r.True(t, isClosed(c.ResumedChan()))
+
+	c.Pause()
+	c.Pause()
+	r.True(t, isClosed(paused))
+	r.True(t, isClosed(c.PausedChan()))
+	resumed := c.ResumedChan()
+	r.False(t, isClosed(resumed))
+	r.False(t, isClosed(c.ClosingChan()))
+
+	c.Resume()
+	c.Resume()
+	r.True(t, isClosed(resumed))
+	r.False(t, isClosed(c.PausedChan()))
+
+	// A routine stops while paused and continues once resumed.
+	var (
+		work  = make(chan int)
+		state = make(chan string)
+	)
+	c.RunCloserRoutine(func() error {
+		for {
+			select {
+			case <-c.ClosingChan():
+				return nil
+			case <-c.PausedChan():
+				state <- "paused"
+				select {
+				case <-c.ResumedChan():
+					state <- "resumed"
+				case <-c.ClosingChan():
+					return nil
+				}
+			case <-work:
+			}
+		}
+	})
+	work <- 1
+	c.Pause()
+	r.Equal(t, "paused", <-state)
+	c.Resume()
+	r.Equal(t, "resumed", <-state)
+	work <- 2
+
+	// Pausing a closing closer is a no-op.
+	r.NoError(t, c.Close())
+	paused = c.PausedChan()
+	c.Pause()
+	r.False(t, isClosed(paused))
+}
@@ -0,0 +1,113 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "sort"
+
+// A ClosePhase registers close funcs into a named phase of a closer, see Closer.Phase().
+type ClosePhase interface {
+	// OnClose adds the functions to the phase.
+	// The funcs of a phase are executed in LIFO order.
+	OnClose(f ...CloseFunc)
+}
+
+// A closePhase implements the ClosePhase interface for a closer.
+type closePhase struct {
+	c    *closer
+	name string
+}
+
+// Implements the ClosePhase interface.
+func (p closePhase) OnClose(f ...CloseFunc) {
+	c := p.c
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.checkMaxCloseFuncs(len(f))
+	if c.closeFuncPhases == nil {
+		c.closeFuncPhases = make(map[int]string)
+	}
+	for _, ff := range f {
+		c.closeFuncPhases[len(c.closeFuncs)] = p.name
+		c.closeFuncs = append(c.closeFuncs, ff)
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) Phase(name string) ClosePhase {
+	return closePhase{c: c, name: name}
+}
+
+// Implements the Closer interface.
+func (c *closer) SetPhaseOrder(names ...string) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.phaseOrder = append([]string(nil), names...)
+}
+
+// orderPhases sorts the execution order of the close funcs by their phases.
+// Funcs without a phase are executed first, followed by the phases in the given
+// phase order and the remaining phases in the order of their first registration.
+// The order within a phase is kept.
+func orderPhases(order []int, phases map[int]string, phaseOrder []string) []int {
+	if len(phases) == 0 {
+		return order
+	}
+
+	rank := make(map[string]int, len(phaseOrder))
+	for _, name := range phaseOrder {
+		if _, ok := rank[name]; !ok {
+			rank[name] = len(rank) + 1
+		}
+	}
+
+	// Rank the unordered phases by the index of their first func.
+	indexes := make([]int, 0, len(phases))
+	for i := range phases {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if _, ok := rank[phases[i]]; !ok {
+			rank[phases[i]] = len(rank) + 1
+		}
+	}
+
+	rankOf := func(i int) int {
+		name, ok := phases[i]
+		if !ok {
+			return 0
+		}
+		return rank[name]
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rankOf(order[a]) < rankOf(order[b])
+	})
+	return order
+}
@@ -0,0 +1,88 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_Phase(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c     = closer.New()
+		order []string
+	)
+	add := func(register func(...closer.CloseFunc), name string) {
+		register(func() error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	c.SetPhaseOrder("network", "storage")
+	add(c.Phase("storage").OnClose, "storage1")
+	add(c.Phase("metrics").OnClose, "metrics1")
+	add(c.Phase("network").OnClose, "network1")
+	add(c.OnClose, "plain1")
+	add(c.Phase("storage").OnClose, "storage2")
+	add(c.Phase("network").OnClose, "network2")
+	add(c.OnClose, "plain2")
+
+	r.Len(t, c.ClosePlan(), 7)
+	r.NoError(t, c.Close())
+	r.Equal(t, []string{
+		"plain2", "plain1",
+		"network2", "network1",
+		"storage2", "storage1",
+		"metrics1",
+	}, order)
+}
+
+func TestCloser_PhaseUnordered(t *testing.T) {
+	t.Parallel()
+
+	// Without an order, the phases run in the order of their registration.
+	var (
+		c     = closer.New()
+		order []string
+	)
+	c.Phase("b").OnClose(func() error {
+		order = append(order, "b")
+		return nil
+	})
+	c.Phase("a").OnClose(func() error {
+		order = append(order, "a")
+		return nil
+	})
+	r.NoError(t, c.Close())
+	r.Equal(t, []string{"b", "a"}, order)
+}
@@ -0,0 +1,117 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"reflect"
+	"runtime"
+)
+
+// A Phase identifies a part of the closing order.
+type Phase string
+
+const (
+	// PhaseClosing contains the OnClosing funcs.
+	PhaseClosing Phase = "closing"
+	// PhaseBeforeChildren contains the OnBeforeChildrenClose funcs.
+	PhaseBeforeChildren Phase = "beforeChildren"
+	// PhaseChildren contains the closing of the children.
+	PhaseChildren Phase = "children"
+	// PhaseClose contains the OnClose funcs.
+	PhaseClose Phase = "close"
+	// PhaseFinal contains the OnCloseFinal funcs.
+	PhaseFinal Phase = "final"
+	// PhaseLast contains the OnCloseLast funcs.
+	PhaseLast Phase = "last"
+)
+
+// A PlanStep describes a single step of the closing order.
+type PlanStep struct {
+	// Phase is the part of the closing order the step is executed in.
+	Phase Phase
+	// Name is the function name of a func or the closer name of a child.
+	Name string
+}
+
+// Implements the Closer interface.
+func (c *closer) ClosePlan() []PlanStep {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// Skip the closing funcs that have already been executed by BeginClosing().
+	closingFuncs := c.closingFuncs[c.softClosingFuncs:]
+
+	plan := make([]PlanStep, 0, len(closingFuncs)+len(c.beforeChildrenFuncs)+len(c.children)+len(c.closeFuncs)+len(c.finalFuncs)+len(c.lastFuncs))
+	for i := len(closingFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, PlanStep{Phase: PhaseClosing, Name: funcName(closingFuncs[i])})
+	}
+	for i := len(c.beforeChildrenFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, PlanStep{Phase: PhaseBeforeChildren, Name: funcName(c.beforeChildrenFuncs[i])})
+	}
+	for _, child := range c.children {
+		// Lock order: parent before child.
+		child.mx.Lock()
+		plan = append(plan, PlanStep{Phase: PhaseChildren, Name: child.opts.name})
+		child.mx.Unlock()
+	}
+	order, _ := orderCloseFuncs(len(c.closeFuncs), c.closeFuncDeps)
+	order = orderPhases(order, c.closeFuncPhases, c.phaseOrder)
+	for _, i := range order {
+		plan = append(plan, PlanStep{Phase: PhaseClose, Name: funcName(c.closeFuncs[i])})
+	}
+	for i := len(c.finalFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, PlanStep{Phase: PhaseFinal, Name: funcName(c.finalFuncs[i])})
+	}
+	for i := len(c.lastFuncs) - 1; i >= 0; i-- {
+		plan = append(plan, PlanStep{Phase: PhaseLast, Name: funcName(c.lastFuncs[i])})
+	}
+	return plan
+}
+
+// Implements the Closer interface.
+func (c *closer) FuncCounts() map[Phase]int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return map[Phase]int{
+		PhaseClosing:        len(c.closingFuncs) - c.softClosingFuncs,
+		PhaseBeforeChildren: len(c.beforeChildrenFuncs),
+		PhaseClose:          len(c.closeFuncs),
+		PhaseFinal:          len(c.finalFuncs),
+		PhaseLast:           len(c.lastFuncs),
+	}
+}
+
+// funcName returns the name of the given function.
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return ""
+	}
+	return fn.Name()
+}
@@ -0,0 +1,124 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_ClosePlan(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c        = closer.New(closer.WithName("root"))
+		executed []closer.PlanStep
+	)
+	r.Equal(t, "root", c.Name())
+
+	name := func(f closer.CloseFunc) string {
+		return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	}
+	register := func(phase closer.Phase, add func(...closer.CloseFunc)) {
+		var f closer.CloseFunc
+		f = func() error {
+			executed = append(executed, closer.PlanStep{Phase: phase, Name: name(f)})
+			return nil
+		}
+		add(f)
+	}
+
+	for i := 0; i < 2; i++ {
+		register(closer.PhaseClosing, c.OnClosing)
+		register(closer.PhaseBeforeChildren, c.OnBeforeChildrenClose)
+		register(closer.PhaseClose, c.OnClose)
+	}
+	for _, n := range []string{"a", "b"} {
+		n := n
+		child := c.CloserOneWay(closer.WithName(n))
+		child.OnClose(func() error {
+			executed = append(executed, closer.PlanStep{Phase: closer.PhaseChildren, Name: n})
+			return nil
+		})
+	}
+
+	plan := c.ClosePlan()
+	r.Len(t, plan, 8)
+	r.Equal(t, closer.PlanStep{Phase: closer.PhaseChildren, Name: "a"}, plan[4])
+	r.Equal(t, closer.PlanStep{Phase: closer.PhaseChildren, Name: "b"}, plan[5])
+
+	// The plan must not change the closer.
+	r.Equal(t, plan, c.ClosePlan())
+	r.False(t, c.IsClosing())
+	r.Empty(t, executed)
+
+	r.NoError(t, c.Close())
+	r.Equal(t, plan, executed)
+	r.Empty(t, c.ClosePlan())
+}
+
+func TestCloser_FuncCounts(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	r.Equal(t, map[closer.Phase]int{
+		closer.PhaseClosing:        0,
+		closer.PhaseBeforeChildren: 0,
+		closer.PhaseClose:          0,
+		closer.PhaseFinal:          0,
+		closer.PhaseLast:           0,
+	}, c.FuncCounts())
+
+	nop := func() error { return nil }
+	c.OnClosing(nop, nop)
+	c.OnBeforeChildrenClose(nop)
+	c.OnClose(nop, nop)
+	c.Phase("storage").OnClose(nop)
+	c.OnCloseFinal(func([]error) error { return nil })
+	c.OnCloseLast(nop)
+	_ = c.CloserOneWay()
+
+	counts := c.FuncCounts()
+	r.Equal(t, 2, counts[closer.PhaseClosing])
+	r.Equal(t, 1, counts[closer.PhaseBeforeChildren])
+	r.Equal(t, 3, counts[closer.PhaseClose])
+	r.Equal(t, 1, counts[closer.PhaseFinal])
+	r.Equal(t, 1, counts[closer.PhaseLast])
+	r.Zero(t, counts[closer.PhaseChildren])
+
+	// The closing funcs executed by BeginClosing() are not counted.
+	r.NoError(t, c.BeginClosing())
+	r.Zero(t, c.FuncCounts()[closer.PhaseClosing])
+
+	r.NoError(t, c.Close())
+	r.Zero(t, c.FuncCounts()[closer.PhaseClose])
+}
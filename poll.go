@@ -0,0 +1,157 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// maxPollEntries is the maximum number of contexts watched by a single poller.
+// reflect.Select scans all cases on every wake up, so the set is split into
+// batches to keep each scan bounded.
+const maxPollEntries = 1024
+
+var (
+	// pollMx protects pollers and the entries of each poller.
+	pollMx  sync.Mutex
+	pollers []*ctxPoller
+)
+
+// A ctxPoller watches the contexts of many closers within a single goroutine.
+// The goroutine exits, once no entry is left.
+type ctxPoller struct {
+	entries []*pollEntry
+	wake    chan struct{}
+}
+
+// A pollEntry closes the closer, once done is closed. The entry is dropped,
+// if the closer starts closing on its own.
+type pollEntry struct {
+	c    *closer
+	done <-chan struct{}
+}
+
+// Implements the Closer interface.
+func (c *closer) BindContextPoll(ctx context.Context) {
+	done := ctx.Done()
+	if done == nil {
+		// The context is never done.
+		return
+	}
+
+	pollMx.Lock()
+	var p *ctxPoller
+	for _, pp := range pollers {
+		if len(pp.entries) < maxPollEntries {
+			p = pp
+			break
+		}
+	}
+	if p == nil {
+		p = &ctxPoller{wake: make(chan struct{}, 1)}
+		pollers = append(pollers, p)
+		go p.run()
+	}
+	p.entries = append(p.entries, &pollEntry{c: c, done: done})
+	pollMx.Unlock()
+
+	// Wake up the poller to include the new entry.
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run selects over the entries of the poller, until no entry is left.
+func (p *ctxPoller) run() {
+	var cases []reflect.SelectCase
+	for {
+		pollMx.Lock()
+		if len(p.entries) == 0 {
+			p.remove()
+			pollMx.Unlock()
+			return
+		}
+		// Each entry has two cases: the done chan and the closing chan.
+		entries := append([]*pollEntry(nil), p.entries...)
+		cases = append(cases[:0], reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(p.wake)})
+		for _, e := range entries {
+			cases = append(cases,
+				reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.done)},
+				reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(e.c.closingChan)},
+			)
+		}
+		pollMx.Unlock()
+
+		chosen, _, _ := reflect.Select(cases)
+		if chosen == 0 {
+			// New entries have been added.
+			continue
+		}
+		e := entries[(chosen-1)/2]
+
+		pollMx.Lock()
+		p.drop(e)
+		pollMx.Unlock()
+
+		if (chosen-1)%2 == 0 {
+			// Do not block the poller by the closing order.
+			go e.c.Close_()
+		}
+	}
+}
+
+// drop removes the entry from the poller.
+// The pollMx must be locked.
+func (p *ctxPoller) drop(e *pollEntry) {
+	for i, pe := range p.entries {
+		if pe == e {
+			last := len(p.entries) - 1
+			p.entries[i] = p.entries[last]
+			p.entries[last] = nil
+			p.entries = p.entries[:last]
+			return
+		}
+	}
+}
+
+// remove removes the poller from the pollers.
+// The pollMx must be locked.
+func (p *ctxPoller) remove() {
+	for i, pp := range pollers {
+		if pp == p {
+			last := len(pollers) - 1
+			pollers[i] = pollers[last]
+			pollers[last] = nil
+			pollers = pollers[:last]
+			return
+		}
+	}
+}
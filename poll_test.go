@@ -0,0 +1,87 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_BindContextPoll(t *testing.T) {
+	t.Parallel()
+
+	// Bind enough closers to require multiple pollers.
+	const n = 3000
+	var (
+		closers = make([]closer.Closer, n)
+		cancels = make([]context.CancelFunc, n)
+	)
+	for i := range closers {
+		var ctx context.Context
+		ctx, cancels[i] = context.WithCancel(context.Background())
+		closers[i] = closer.New()
+		closers[i].BindContextPoll(ctx)
+	}
+
+	// Cancel every other context and close the remaining closers on their own.
+	for i := 0; i < n; i += 2 {
+		cancels[i]()
+	}
+	for i := 0; i < n; i += 2 {
+		select {
+		case <-closers[i].ClosedChan():
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		r.False(t, closers[i].IsClosing())
+		r.NoError(t, closers[i].Close())
+		cancels[i]()
+	}
+
+	// A done context closes the closer immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := closer.New()
+	c.BindContextPoll(ctx)
+	select {
+	case <-c.ClosedChan():
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	// A context, which is never done, is ignored.
+	c = closer.New()
+	c.BindContextPoll(context.Background())
+	r.False(t, c.IsClosing())
+}
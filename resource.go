@@ -0,0 +1,55 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// A Resource ties a value to a closer.
+// The value is cleaned up once the closer closes.
+type Resource[T any] struct {
+	Closer
+
+	value T
+}
+
+// NewResource creates a new resource for the given value, configured with the given options.
+// The cleanup func is called exactly once with the value during Close().
+// Its error is joined with the closer's other errors.
+func NewResource[T any](value T, cleanup func(T) error, opts ...Option) *Resource[T] {
+	r := &Resource[T]{
+		Closer: newCloser(3, opts...),
+		value:  value,
+	}
+	r.OnClose(func() error {
+		return cleanup(r.value)
+	})
+	return r
+}
+
+// Value returns the value of the resource.
+func (r *Resource[T]) Value() T {
+	return r.value
+}
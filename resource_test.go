@@ -0,0 +1,69 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestResource(t *testing.T) {
+	t.Parallel()
+
+	// Cleanup an int value.
+	var (
+		cleaned atomic.Int64
+		errInt  = errors.New("int cleanup")
+	)
+	ri := closer.NewResource(42, func(v int) error {
+		cleaned.Add(int64(v))
+		return errInt
+	})
+	r.Equal(t, 42, ri.Value())
+
+	for i := 0; i < 3; i++ {
+		r.ErrorIs(t, ri.Close(), errInt)
+	}
+	r.Equal(t, int64(42), cleaned.Load())
+	r.ErrorIs(t, ri.CloserError(), errInt)
+
+	// Cleanup a file.
+	f, err := os.Create(filepath.Join(t.TempDir(), "resource"))
+	r.NoError(t, err)
+
+	rf := closer.NewResource(f, (*os.File).Close)
+	r.Same(t, f, rf.Value())
+	r.NoError(t, rf.Close())
+	r.ErrorIs(t, f.Close(), os.ErrClosed)
+}
@@ -52,21 +52,15 @@ func newBatch(cl closer.Closer) *batch {
 }
 
 func (b *batch) run() {
-	// Fire up several routines and make sure
-	b.Closer.CloserAddWait(numberBatchRoutines)
-	for i := 0; i < numberBatchRoutines; i++ {
-		go b.workRoutine()
-	}
+	// Fire up several routines and make sure our closer waits for each of them when closing.
+	// If one work routine dies, we let the others continue their work.
+	b.SpawnWorkers(numberBatchRoutines, b.workRoutine)
 
 	fmt.Println("batch up and running...")
 }
 
-func (b *batch) workRoutine() {
-	// If one work routine dies, we let the others continue their work,
-	// so do not close on defer here, but still decrement the wait group.
-	defer b.CloserDone()
-
+func (b *batch) workRoutine(stop <-chan struct{}) {
 	// Normally, some work is performed here...
-	<-b.ClosingChan()
+	<-stop
 	fmt.Println("batch routine shutting down")
 }
@@ -0,0 +1,87 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "errors"
+
+// A benignError marks an error as benign, see Benign().
+type benignError struct {
+	err error
+}
+
+func (e *benignError) Error() string { return e.err.Error() }
+func (e *benignError) Unwrap() error { return e.err }
+
+// Benign marks the given error as benign, e.g. a "connection already closed" error
+// of a cleanup. Benign errors are part of the closer's errors, but are removed by
+// FilterBenign() and Closer.SevereError(). All other errors are severe.
+// The returned error wraps err. Returns nil, if err is nil.
+func Benign(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &benignError{err: err}
+}
+
+// FilterBenign returns the given error without the errors marked with Benign().
+// Joined errors, like the errors of a closer, are filtered one by one.
+// Returns nil, if all errors are benign.
+func FilterBenign(err error) error {
+	if err == nil {
+		return nil
+	}
+	if u, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range u.Unwrap() {
+			if e = FilterBenign(e); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	var b *benignError
+	if errors.As(err, &b) {
+		return nil
+	}
+	return err
+}
+
+// Implements the Closer interface.
+func (c *closer) OnCloseBenign(f ...CloseFunc) {
+	for _, ff := range f {
+		ff := ff
+		c.OnClose(func() error {
+			return Benign(ff())
+		})
+	}
+}
+
+// Implements the Closer interface.
+func (c *closer) SevereError() error {
+	return FilterBenign(c.CloserError())
+}
@@ -0,0 +1,88 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_SevereError(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errFlush       = errors.New("failed to flush data")
+		errConnClosed  = errors.New("connection already closed")
+		errChildSevere = errors.New("child severe")
+		c              = closer.New()
+		child          = c.CloserOneWay()
+	)
+	c.OnClose(func() error { return errFlush })
+	c.OnCloseBenign(func() error { return errConnClosed })
+	c.OnClosing(func() error { return closer.Benign(errConnClosed) })
+	child.OnClose(func() error { return errChildSevere })
+	child.OnCloseBenign(func() error { return errConnClosed })
+	r.NoError(t, c.SevereError())
+
+	err := c.Close()
+	r.ErrorIs(t, err, errConnClosed)
+	r.ErrorIs(t, err, errFlush)
+
+	severe := c.SevereError()
+	r.ErrorIs(t, severe, errFlush)
+	r.ErrorIs(t, severe, errChildSevere)
+	r.NotErrorIs(t, severe, errConnClosed)
+
+	// Only benign errors.
+	c = closer.New()
+	c.OnCloseBenign(func() error { return errConnClosed }, func() error { return nil })
+	r.ErrorIs(t, c.Close(), errConnClosed)
+	r.NoError(t, c.SevereError())
+}
+
+func TestFilterBenign(t *testing.T) {
+	t.Parallel()
+
+	errA := errors.New("a")
+	r.NoError(t, closer.FilterBenign(nil))
+	r.Nil(t, closer.Benign(nil))
+	r.ErrorIs(t, closer.Benign(errA), errA)
+	r.Equal(t, "a", closer.Benign(errA).Error())
+	r.NoError(t, closer.FilterBenign(closer.Benign(errA)))
+	r.NoError(t, closer.FilterBenign(fmt.Errorf("wrapped: %w", closer.Benign(errA))))
+	r.Equal(t, errA, closer.FilterBenign(errA))
+
+	errB := errors.New("b")
+	err := closer.FilterBenign(errors.Join(closer.Benign(errA), errors.Join(errB, closer.Benign(errA))))
+	r.ErrorIs(t, err, errB)
+	r.NotErrorIs(t, err, errA)
+}
@@ -0,0 +1,109 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestShutdownContext(t *testing.T) {
+	t.Parallel()
+
+	const budget = 200 * time.Millisecond
+
+	// Each level would block longer than the budget on its own.
+	var (
+		root       = closer.New()
+		child      = root.CloserOneWay()
+		grandchild = child.CloserOneWay()
+		remaining  = make(chan time.Duration, 1)
+	)
+	grandchild.CloserAddWait(1)
+	child.OnCloseCtx(func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		remaining <- time.Until(deadline)
+		<-ctx.Done()
+		return nil
+	})
+	root.OnCloseCtx(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	start := time.Now()
+	err := closer.ShutdownContext(ctx, root)
+	elapsed := time.Since(start)
+	r.ErrorIs(t, err, context.DeadlineExceeded)
+	r.Less(t, elapsed, 2*budget)
+
+	// The remaining steps are finished without waiting.
+	select {
+	case <-root.ClosedChan():
+	case <-time.After(budget):
+		t.Fatal("timed out")
+	}
+	r.True(t, grandchild.IsClosed())
+
+	// The child only received the budget left by its descendants.
+	r.LessOrEqual(t, <-remaining, 10*time.Millisecond)
+}
+
+func TestShutdownContext_Blocked(t *testing.T) {
+	t.Parallel()
+
+	// A func not honoring the context does not extend the budget.
+	var (
+		root    = closer.New()
+		release = make(chan struct{})
+	)
+	root.OnClose(func() error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r.ErrorIs(t, closer.ShutdownContext(ctx, root), context.DeadlineExceeded)
+	r.True(t, root.IsClosing())
+	r.False(t, root.IsClosed())
+
+	close(release)
+	<-root.ClosedChan()
+
+	// A clean close returns without an error.
+	r.NoError(t, closer.ShutdownContext(context.Background(), closer.New()))
+}
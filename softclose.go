@@ -0,0 +1,104 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "errors"
+
+// Implements the Closer interface.
+func (c *closer) BeginClosing() error {
+	c.mx.Lock()
+	if c.IsClosing() {
+		c.mx.Unlock()
+		return ErrClosing
+	} else if c.softClosing {
+		c.mx.Unlock()
+		return nil
+	}
+	c.softClosing = true
+	close(c.softClosingChan)
+	closingFuncs := c.closingFuncs
+	c.softClosingFuncs = len(closingFuncs)
+	done := make(chan struct{})
+	c.softClosingDone = done
+	c.mx.Unlock()
+
+	// Execute all closing funcs of this closer in LIFO order.
+	var err error
+	for i := len(closingFuncs) - 1; i >= 0; i-- {
+		err = errors.Join(err, callCloseFunc(closingFuncs[i]))
+	}
+
+	c.mx.Lock()
+	c.softClosingErr = err
+	close(done)
+	c.mx.Unlock()
+
+	return err
+}
+
+// Implements the Closer interface.
+func (c *closer) AbortClosing() error {
+	c.mx.Lock()
+	if c.IsClosing() {
+		c.mx.Unlock()
+		return ErrClosing
+	} else if !c.softClosing {
+		c.mx.Unlock()
+		return nil
+	}
+	done := c.softClosingDone
+	c.mx.Unlock()
+
+	// Wait for the closing funcs of BeginClosing() to return.
+	<-done
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// Check again, the state might have changed in the meantime.
+	if c.IsClosing() {
+		return ErrClosing
+	} else if !c.softClosing || c.softClosingDone != done {
+		return nil
+	}
+
+	c.softClosing = false
+	c.softClosingChan = make(chan struct{})
+	c.softClosingFuncs = 0
+	c.softClosingErr = nil
+	c.softClosingDone = nil
+	return nil
+}
+
+// Implements the Closer interface.
+func (c *closer) SoftClosingChan() <-chan struct{} {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.softClosingChan
+}
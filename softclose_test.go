@@ -0,0 +1,104 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_BeginClosing(t *testing.T) {
+	t.Parallel()
+
+	var (
+		calls      atomic.Int64
+		errClosing = errors.New("closing")
+		c          = closer.New()
+	)
+	c.OnClosing(func() error {
+		calls.Add(1)
+		return errClosing
+	})
+	r.NoError(t, c.AbortClosing())
+
+	softChan := c.SoftClosingChan()
+	r.ErrorIs(t, c.BeginClosing(), errClosing)
+	r.Equal(t, int64(1), calls.Load())
+	r.NoError(t, c.BeginClosing())
+	r.Equal(t, int64(1), calls.Load())
+
+	// Only the soft closing channel is closed.
+	select {
+	case <-softChan:
+	default:
+		t.Fatal("soft closing chan should be closed")
+	}
+	r.False(t, c.IsClosing())
+
+	// Abort and return to the open state.
+	r.NoError(t, c.AbortClosing())
+	softChan = c.SoftClosingChan()
+	select {
+	case <-softChan:
+		t.Fatal("soft closing chan should be open")
+	default:
+	}
+
+	// The closing funcs run again on the next soft close, but not on the following Close.
+	r.ErrorIs(t, c.BeginClosing(), errClosing)
+	r.Equal(t, int64(2), calls.Load())
+
+	var closeCalled atomic.Bool
+	c.OnClose(func() error {
+		closeCalled.Store(true)
+		return nil
+	})
+	r.ErrorIs(t, c.Close(), errClosing)
+	r.Equal(t, int64(2), calls.Load())
+	r.True(t, closeCalled.Load())
+
+	r.ErrorIs(t, c.AbortClosing(), closer.ErrClosing)
+	r.ErrorIs(t, c.BeginClosing(), closer.ErrClosing)
+}
+
+func TestCloser_SoftClosingChan(t *testing.T) {
+	t.Parallel()
+
+	// A close also closes the soft closing chan.
+	c := closer.New()
+	c.Close_()
+	select {
+	case <-c.SoftClosingChan():
+	default:
+		t.Fatal("soft closing chan should be closed")
+	}
+}
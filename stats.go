@@ -0,0 +1,101 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Stats is a consistent snapshot of a closer's state, see Closer.Stats().
+type Stats struct {
+	// State is the lifecycle state of the closer.
+	State State
+	// NumChildren is the number of direct children.
+	NumChildren int
+	// NumCloseFuncs is the number of registered OnClose funcs,
+	// which have not been run yet.
+	NumCloseFuncs int
+	// PendingWaits is the current value of the wait counter.
+	PendingWaits int
+	// CloseDuration is the duration of the close, see Closer.CloseDuration().
+	CloseDuration time.Duration
+	// ClosedAt is the time the closer was closed.
+	// It is zero, if the closer is not yet closed.
+	ClosedAt time.Time
+	// Name is the name of the closer, see WithName().
+	Name string
+	// CloseReason is the reason recorded by CloseWithReason(),
+	// see Closer.CloseReasonText().
+	CloseReason string
+}
+
+// Implements the Closer interface.
+func (c *closer) Stats() Stats {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// The closing and closed chans are closed with the mutex held,
+	// hence the state is consistent with the other values.
+	return Stats{
+		State:         c.State(),
+		NumChildren:   len(c.children),
+		NumCloseFuncs: len(c.closeFuncs),
+		PendingWaits:  int(c.waitCount),
+		CloseDuration: c.closeDuration(),
+		ClosedAt:      c.closedAt,
+		Name:          c.opts.name,
+		CloseReason:   c.closeReason,
+	}
+}
+
+// statsJSON is the JSON representation of a closer, see Closer.MarshalJSON().
+type statsJSON struct {
+	Name         string     `json:"name"`
+	State        string     `json:"state"`
+	Children     int        `json:"children"`
+	PendingWaits int        `json:"pendingWaits"`
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+}
+
+// Implements the Closer interface.
+func (c *closer) MarshalJSON() ([]byte, error) {
+	s := c.Stats()
+	v := statsJSON{
+		Name:         s.Name,
+		State:        s.State.String(),
+		Children:     s.NumChildren,
+		PendingWaits: s.PendingWaits,
+		Reason:       s.CloseReason,
+	}
+	if !s.ClosedAt.IsZero() {
+		v.ClosedAt = &s.ClosedAt
+	}
+	return json.Marshal(v)
+}
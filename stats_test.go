@@ -0,0 +1,151 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New(closer.WithName("stats"))
+	c.OnClose(func() error { return nil })
+	c.CloserAddWait(2)
+	_ = c.CloserOneWay()
+
+	s := c.Stats()
+	r.Equal(t, closer.StateOpen, s.State)
+	r.Equal(t, 1, s.NumChildren)
+	r.Equal(t, 1, s.NumCloseFuncs)
+	r.Equal(t, 2, s.PendingWaits)
+	r.Zero(t, s.CloseDuration)
+	r.True(t, s.ClosedAt.IsZero())
+	r.Equal(t, "stats", s.Name)
+
+	c.CloserDone()
+	c.CloserDone()
+	r.NoError(t, c.Close())
+
+	s = c.Stats()
+	r.Equal(t, closer.StateClosed, s.State)
+	r.Zero(t, s.NumChildren)
+	r.Zero(t, s.NumCloseFuncs)
+	r.Zero(t, s.PendingWaits)
+	r.False(t, s.ClosedAt.IsZero())
+	r.Equal(t, c.CloseDuration(), s.CloseDuration)
+}
+
+func TestStats_Consistent(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+
+	// Mutate the closer concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !c.IsClosing() {
+				c.OnClose(func() error { return nil })
+				cc := c.CloserOneWay()
+				cc.Close_()
+			}
+		}()
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.Close_()
+	}()
+
+	for {
+		s := c.Stats()
+		switch s.State {
+		case closer.StateOpen:
+			r.Zero(t, s.CloseDuration)
+			r.True(t, s.ClosedAt.IsZero())
+		case closer.StateClosing:
+			r.True(t, s.ClosedAt.IsZero())
+		case closer.StateClosed:
+			r.False(t, s.ClosedAt.IsZero())
+			r.Equal(t, c.CloseDuration(), s.CloseDuration)
+		}
+		if s.State == closer.StateClosed {
+			break
+		}
+	}
+	wg.Wait()
+}
+
+func TestCloser_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	type closerJSON struct {
+		Name         string     `json:"name"`
+		State        string     `json:"state"`
+		Children     int        `json:"children"`
+		PendingWaits int        `json:"pendingWaits"`
+		ClosedAt     *time.Time `json:"closedAt"`
+		Reason       string     `json:"reason"`
+	}
+
+	c := closer.New(closer.WithName("json"))
+	c.CloserAddWait(1)
+	_ = c.CloserOneWay()
+
+	data, err := json.Marshal(c)
+	r.NoError(t, err)
+	var v closerJSON
+	r.NoError(t, json.Unmarshal(data, &v))
+	r.Equal(t, closerJSON{Name: "json", State: "open", Children: 1, PendingWaits: 1}, v)
+
+	c.CloserDone()
+	r.NoError(t, c.CloseWithReason("shutdown"))
+
+	data, err = json.Marshal(c)
+	r.NoError(t, err)
+	v = closerJSON{}
+	r.NoError(t, json.Unmarshal(data, &v))
+	r.Equal(t, "closed", v.State)
+	r.Equal(t, "shutdown", v.Reason)
+	r.Zero(t, v.Children)
+	r.NotNil(t, v.ClosedAt)
+	r.WithinDuration(t, c.Stats().ClosedAt, *v.ClosedAt, 0)
+
+	// Closers are encoded as a single field of a structured log entry.
+	data, err = json.Marshal(map[string]any{"c": c})
+	r.NoError(t, err)
+	r.Contains(t, string(data), `{"c":{"name":"json","state":"closed"`)
+}
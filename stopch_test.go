@@ -0,0 +1,72 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestStopCh(t *testing.T) {
+	t.Parallel()
+
+	c := closer.New()
+	stopCh := closer.StopCh(c)
+	r.Equal(t, c.ClosingChan(), stopCh)
+	r.Equal(t, c.ClosingChan(), c.OnStopCh())
+
+	c.Close_()
+	select {
+	case <-stopCh:
+	default:
+		t.Fatal("stop chan should be closed")
+	}
+}
+
+func TestNewFromStopCh(t *testing.T) {
+	t.Parallel()
+
+	stopCh := make(chan struct{})
+	c := closer.NewFromStopCh(stopCh, closer.WithName("informer"))
+	r.Equal(t, "informer", c.Name())
+	r.False(t, c.IsClosing())
+
+	close(stopCh)
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+
+	// Closing the closer first must not require the stop channel.
+	c = closer.NewFromStopCh(make(chan struct{}))
+	r.NoError(t, c.Close())
+}
@@ -0,0 +1,46 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import "context"
+
+// A CloseTracer traces the closing order of closers, see WithCloseTracer().
+// It allows to integrate tracing libraries without adding them as dependencies.
+type CloseTracer interface {
+	// StartClose is called, once the closer starts its closing order.
+	// The returned context is passed to the closing order, including the
+	// closes of the children and the funcs of OnCloseCtx().
+	// The returned func is called with the close error, once the closer is closed.
+	// At this point, the close reason of c is final, see Closer.CloseReasonText().
+	StartClose(ctx context.Context, c Closer) (context.Context, func(err error))
+
+	// StartStep is called before each func of the closing order with the context
+	// returned by StartClose(). The closes of the children are traced by their own
+	// StartClose() calls instead. The returned func is called with the error of the step.
+	StartStep(ctx context.Context, step PlanStep) func(err error)
+}
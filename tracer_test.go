@@ -0,0 +1,112 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+type tracerKey struct{}
+
+// testTracer records the traced closers and steps with the name of their parent span.
+type testTracer struct {
+	mx      sync.Mutex
+	spans   []string
+	parent  map[string]string
+	errs    map[string]error
+	reasons map[string]string
+}
+
+func (tt *testTracer) add(ctx context.Context, name string) {
+	tt.mx.Lock()
+	defer tt.mx.Unlock()
+
+	parent, _ := ctx.Value(tracerKey{}).(string)
+	tt.spans = append(tt.spans, name)
+	tt.parent[name] = parent
+}
+
+func (tt *testTracer) end(name string) func(err error) {
+	return func(err error) {
+		tt.mx.Lock()
+		defer tt.mx.Unlock()
+
+		tt.errs[name] = err
+	}
+}
+
+func (tt *testTracer) StartClose(ctx context.Context, c closer.Closer) (context.Context, func(err error)) {
+	tt.add(ctx, c.Name())
+	end := tt.end(c.Name())
+	return context.WithValue(ctx, tracerKey{}, c.Name()), func(err error) {
+		tt.mx.Lock()
+		tt.reasons[c.Name()] = c.CloseReasonText()
+		tt.mx.Unlock()
+		end(err)
+	}
+}
+
+func (tt *testTracer) StartStep(ctx context.Context, step closer.PlanStep) func(err error) {
+	name := string(step.Phase)
+	tt.add(ctx, name)
+	return tt.end(name)
+}
+
+func TestCloser_CloseTracer(t *testing.T) {
+	t.Parallel()
+
+	tt := &testTracer{parent: map[string]string{}, errs: map[string]error{}, reasons: map[string]string{}}
+	errChild := errors.New("child")
+
+	p := closer.New(closer.WithName("root"), closer.WithCloseTracer(tt))
+	p.OnClosing(func() error { return nil })
+	child := p.CloserOneWay(closer.WithName("child"))
+	child.OnClose(func() error { return errChild })
+
+	var ctxParent string
+	p.OnCloseCtx(func(ctx context.Context) error {
+		ctxParent, _ = ctx.Value(tracerKey{}).(string)
+		return nil
+	})
+
+	r.ErrorIs(t, p.CloseWithReason("shutdown"), errChild)
+	r.Equal(t, []string{"root", "closing", "child", "close", "close"}, tt.spans)
+	r.Equal(t, "", tt.parent["root"])
+	r.Equal(t, "root", tt.parent["closing"])
+	r.Equal(t, "root", tt.parent["child"])
+	r.Equal(t, "root", ctxParent)
+	r.ErrorIs(t, tt.errs["root"], errChild)
+	r.ErrorIs(t, tt.errs["child"], errChild)
+	r.Equal(t, "shutdown", tt.reasons["root"])
+}
@@ -0,0 +1,104 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+import (
+	"context"
+	"errors"
+)
+
+// WaitN blocks, until at least n of the given closers have closed and returns
+// the joined errors of the first n closed closers in the order they closed.
+// If n exceeds the number of closers, WaitN waits for all of them and if n
+// is not positive, it returns immediately. The goroutines watching the
+// remaining closers exit, once the threshold is met.
+func WaitN(n int, cs ...Closer) error {
+	if n > len(cs) {
+		n = len(cs)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	var (
+		// Buffered, so that no goroutine blocks after the threshold is met.
+		closed = make(chan Closer, len(cs))
+		stop   = make(chan struct{})
+	)
+	defer close(stop)
+
+	for _, c := range cs {
+		go func(c Closer) {
+			select {
+			case <-c.ClosedChan():
+				closed <- c
+			case <-stop:
+			}
+		}(c)
+	}
+
+	errs := make([]error, 0, n)
+	for i := 0; i < n; i++ {
+		errs = append(errs, (<-closed).CloserError())
+	}
+	return errors.Join(errs...)
+}
+
+// AllClosed returns a channel, which is closed once all of the given closers
+// have closed. A single goroutine waits for the closers, which exits once the
+// channel is closed. Without closers, the returned channel is already closed.
+func AllClosed(cs ...Closer) <-chan struct{} {
+	ch := make(chan struct{})
+	if len(cs) == 0 {
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer close(ch)
+		for _, c := range cs {
+			<-c.ClosedChan()
+		}
+	}()
+	return ch
+}
+
+// WaitAll blocks, until all of the given closers have closed, and returns their
+// joined errors in the order of the closers. If the context is done first,
+// the context's error is returned. No goroutine is started.
+func WaitAll(ctx context.Context, cs ...Closer) error {
+	errs := make([]error, 0, len(cs))
+	for _, c := range cs {
+		select {
+		case <-c.ClosedChan():
+			errs = append(errs, c.CloserError())
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
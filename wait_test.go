@@ -0,0 +1,174 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestWaitN(t *testing.T) {
+	t.Parallel()
+
+	newClosers := func() []closer.Closer {
+		return []closer.Closer{closer.New(), closer.New(), closer.New()}
+	}
+	waitN := func(n int, cs ...closer.Closer) <-chan error {
+		ch := make(chan error, 1)
+		go func() { ch <- closer.WaitN(n, cs...) }()
+		return ch
+	}
+	requireBlocked := func(ch <-chan error) {
+		select {
+		case <-ch:
+			t.Fatal("returned early")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	requireReturned := func(ch <-chan error) error {
+		select {
+		case err := <-ch:
+			return err
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out")
+			return nil
+		}
+	}
+
+	t.Run("One", func(t *testing.T) {
+		cs := newClosers()
+		ch := waitN(1, cs...)
+		requireBlocked(ch)
+		errA := errors.New("a")
+		cs[1].CloseWithErr(errA)
+		r.ErrorIs(t, requireReturned(ch), errA)
+	})
+
+	t.Run("Quorum", func(t *testing.T) {
+		cs := newClosers()
+		ch := waitN(2, cs...)
+		r.NoError(t, cs[0].Close())
+		requireBlocked(ch)
+		r.NoError(t, cs[2].Close())
+		r.NoError(t, requireReturned(ch))
+		r.False(t, cs[1].IsClosing())
+	})
+
+	t.Run("All", func(t *testing.T) {
+		cs := newClosers()
+		ch := waitN(len(cs), cs...)
+		r.NoError(t, cs[0].Close())
+		r.NoError(t, cs[1].Close())
+		requireBlocked(ch)
+		r.NoError(t, cs[2].Close())
+		r.NoError(t, requireReturned(ch))
+
+		// A larger n waits for all closers.
+		r.NoError(t, requireReturned(waitN(10, cs...)))
+	})
+
+	t.Run("None", func(t *testing.T) {
+		r.NoError(t, requireReturned(waitN(0, newClosers()...)))
+		r.NoError(t, requireReturned(waitN(1)))
+	})
+}
+
+func TestWaitN_Goroutines(t *testing.T) {
+	// The goroutines of the open closers must exit.
+	cs := make([]closer.Closer, 100)
+	for i := range cs {
+		cs[i] = closer.New()
+	}
+	base := runtime.NumGoroutine()
+
+	r.NoError(t, cs[0].Close())
+	r.NoError(t, closer.WaitN(1, cs...))
+	deadline := time.Now().Add(3 * time.Second)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left", runtime.NumGoroutine()-base)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAllClosed(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errA   = errors.New("a")
+		closed = closer.New()
+		later  = closer.New()
+	)
+	closed.CloseWithErr(errA)
+
+	ch := closer.AllClosed(closed, later)
+	select {
+	case <-ch:
+		t.Fatal("closed early")
+	case <-time.After(50 * time.Millisecond):
+	}
+	r.NoError(t, later.Close())
+	select {
+	case <-ch:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	}
+
+	// Without closers, the chan is closed immediately.
+	<-closer.AllClosed()
+}
+
+func TestWaitAll(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errA   = errors.New("a")
+		errB   = errors.New("b")
+		closed = closer.New()
+		later  = closer.New()
+	)
+	closed.CloseWithErr(errA)
+
+	// The context aborts the wait.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r.ErrorIs(t, closer.WaitAll(ctx, closed, later), context.DeadlineExceeded)
+
+	go later.CloseWithErr(errB)
+	err := closer.WaitAll(context.Background(), closed, later)
+	r.ErrorIs(t, err, errA)
+	r.ErrorIs(t, err, errB)
+	r.NoError(t, closer.WaitAll(context.Background()))
+}
@@ -0,0 +1,63 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer
+
+// Implements the Closer interface.
+func (c *closer) SpawnWorkers(n int, worker func(stop <-chan struct{})) {
+	c.SpawnWorkersErr(n, func(stop <-chan struct{}) error {
+		worker(stop)
+		return nil
+	})
+}
+
+// Implements the Closer interface.
+func (c *closer) SpawnWorkersErr(n int, worker func(stop <-chan struct{}) error) {
+	if n <= 0 {
+		return
+	}
+
+	c.closerAddWait(n, false)
+	for i := 0; i < n; i++ {
+		go func() {
+			// CloserAddWait will also add to a closed closer. Ensure we are not in a closing state.
+			if c.IsClosing() {
+				c.CloserDone()
+				return
+			}
+
+			err := callRoutine(func() error {
+				return worker(c.closingChan)
+			})
+			if err != nil {
+				c.CloseWithErrAndDone(err)
+				return
+			}
+			c.CloserDone()
+		}()
+	}
+}
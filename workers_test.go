@@ -0,0 +1,116 @@
+/*
+ * closer - A simple, thread-safe closer
+ *
+ * The MIT License (MIT)
+ *
+ * Copyright (c) 2019 Roland Singer <roland.singer[at]desertbit.com>
+ * Copyright (c) 2019 Sebastian Borchers <sebastian[at]desertbit.com>
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in all
+ * copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+ * SOFTWARE.
+ */
+
+package closer_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/desertbit/closer/v3"
+	r "github.com/stretchr/testify/require"
+)
+
+func TestCloser_SpawnWorkers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		c       = closer.New()
+		started = make(chan struct{}, 5)
+		stopped atomic.Int64
+	)
+	c.SpawnWorkers(5, func(stop <-chan struct{}) {
+		started <- struct{}{}
+		<-stop
+		time.Sleep(10 * time.Millisecond)
+		stopped.Add(1)
+	})
+	for i := 0; i < 5; i++ {
+		<-started
+	}
+	r.Equal(t, 5, c.PendingWaits())
+
+	// The close waits for all workers.
+	r.NoError(t, c.Close())
+	r.Equal(t, int64(5), stopped.Load())
+	r.Zero(t, c.PendingWaits())
+
+	// A panicking worker closes the closer.
+	c = closer.New()
+	c.SpawnWorkers(2, func(stop <-chan struct{}) {
+		select {
+		case <-stop:
+		case <-time.After(10 * time.Millisecond):
+			panic("worker panic")
+		}
+	})
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), closer.ErrPanic)
+	r.Zero(t, c.PendingWaits())
+}
+
+func TestCloser_SpawnWorkersErr(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errWorker = errors.New("worker")
+		c         = closer.New()
+		done      = make(chan struct{})
+	)
+
+	// A worker returning nil does not close the closer.
+	c.SpawnWorkersErr(1, func(<-chan struct{}) error {
+		close(done)
+		return nil
+	})
+	<-done
+	r.Eventually(t, func() bool { return c.PendingWaits() == 0 }, 3*time.Second, time.Millisecond)
+	r.False(t, c.IsClosing())
+
+	// A worker returning an error closes the closer and stops the others.
+	c.SpawnWorkersErr(3, func(stop <-chan struct{}) error {
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(10 * time.Millisecond):
+			return errWorker
+		}
+	})
+	select {
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	case <-c.ClosedChan():
+	}
+	r.ErrorIs(t, c.CloserError(), errWorker)
+	r.Zero(t, c.PendingWaits())
+}